@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
@@ -10,8 +11,12 @@ import (
 	"time"
 
 	"texas-holdem-server/internal/config"
+	"texas-holdem-server/internal/equity"
+	"texas-holdem-server/internal/game"
 	"texas-holdem-server/internal/matchmaking"
+	"texas-holdem-server/internal/replay"
 	"texas-holdem-server/internal/room"
+	"texas-holdem-server/internal/tournament"
 	"texas-holdem-server/internal/user"
 	"texas-holdem-server/internal/ws"
 )
@@ -24,13 +29,22 @@ func main() {
 	go hub.Run()
 
 	roomManager := room.NewManager(hub)
+	replayService := replay.NewService(100)
+	roomManager.SetHandRecorder(replayService)
 	userService := user.NewService(cfg.JWTSecret)
 	matchService := matchmaking.NewService(roomManager)
 	_ = matchService // Will be used later
+	tournamentService := tournament.NewService(roomManager, userService)
 
-	wsHandler := ws.NewHandler(hub, roomManager)
+	wsHandler := ws.NewHandler(hub, roomManager, tournamentService)
 	userHandler := user.NewHandler(userService)
 
+	if n, err := roomManager.LoadSnapshot(cfg.SnapshotPath); err != nil {
+		log.Printf("Failed to restore rooms: %v", err)
+	} else if n > 0 {
+		log.Printf("Restored %d rooms from %s", n, cfg.SnapshotPath)
+	}
+
 	mux := http.NewServeMux()
 	
 	// WebSocket
@@ -41,6 +55,12 @@ func main() {
 	
 	// Room API
 	mux.HandleFunc("/api/rooms", handleRooms(roomManager))
+	mux.HandleFunc("/api/hands/verify", handleVerifyHand(roomManager))
+	mux.HandleFunc("/api/tournaments", handleTournaments(tournamentService))
+	mux.HandleFunc("/api/icm", handleICM)
+
+	// Equity API
+	mux.HandleFunc("/api/equity", handleEquity)
 	
 	// User API
 	userHandler.RegisterRoutes(mux)
@@ -73,6 +93,11 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	tournamentService.Shutdown()
+	if err := roomManager.SaveSnapshot(cfg.SnapshotPath); err != nil {
+		log.Printf("Failed to save rooms: %v", err)
+	}
+
 	log.Println("Server stopped")
 }
 
@@ -106,6 +131,153 @@ func handleRooms(rm *room.Manager) http.HandlerFunc {
 	}
 }
 
+func handleTournaments(ts *tournament.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if id := r.URL.Query().Get("id"); id != "" {
+			t := ts.Get(id)
+			if t == nil {
+				http.Error(w, "Tournament not found", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(t.Details())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ts.List())
+	}
+}
+
+// handleICM prices a deal: what each stack is worth of the payouts left,
+// e.g. {"stacks": [5000, 3000, 2000], "payouts": [500, 300, 200]}. The
+// method is "icm", the default, or "chip" for a chip chop.
+func handleICM(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Stacks  []int64 `json:"stacks"`
+		Payouts []int64 `json:"payouts"`
+		Method  string  `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	chop := tournament.ICM
+	if req.Method == "chip" {
+		chop = tournament.ChipChop
+	}
+	amounts, err := chop(req.Stacks, req.Payouts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"amounts": amounts})
+}
+
+// handleVerifyHand re-derives the deck of a finished hand from its revealed
+// seeds so a player can check it against the cards they saw.
+func handleVerifyHand(rm *room.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		record, ok := rm.GetShuffleRecord(r.URL.Query().Get("handId"))
+		if !ok {
+			http.Error(w, "Hand not found", http.StatusNotFound)
+			return
+		}
+
+		deck, err := game.DeriveDeck(record)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		cards := make([]string, len(deck))
+		for i, c := range deck {
+			cards[i] = c.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"handId":      record.HandID,
+			"variant":     record.Variant,
+			"seedHash":    record.SeedHash,
+			"serverSeed":  record.ServerSeed,
+			"clientSeeds": record.ClientSeeds,
+			"deck":        cards,
+		})
+	}
+}
+
+// handleEquity computes Hold'em equity for hands or ranges, e.g.
+// {"hands": ["AhKh", "TT+, AQs"], "board": "Ks7d2c"}.
+func handleEquity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Hands      []string `json:"hands"`
+		Board      string   `json:"board"`
+		Dead       string   `json:"dead"`
+		Iterations int      `json:"iterations"`
+		Seed       int64    `json:"seed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Iterations > equity.DefaultIterations*10 {
+		req.Iterations = equity.DefaultIterations * 10
+	}
+
+	ranges := make([][]equity.Combo, len(req.Hands))
+	for i, hand := range req.Hands {
+		combos, err := equity.ParseRange(hand)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ranges[i] = combos
+	}
+	board, err := equity.ParseCards(req.Board)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dead, err := equity.ParseCards(req.Dead)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := equity.Calculate(ranges, board, dead, equity.Options{Iterations: req.Iterations, Seed: req.Seed})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
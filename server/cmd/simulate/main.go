@@ -0,0 +1,282 @@
+// Command simulate plays hands in-process as fast as it can, checking the
+// game's invariants after every step. It stops at the first violation and
+// prints the hand's actions and event log; the same flags replay the same
+// hands.
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"texas-holdem-server/internal/ai"
+	"texas-holdem-server/internal/game"
+)
+
+// seededDeck shuffles from the simulation's own generator instead of the
+// hand's server seed, which is random by design.
+type seededDeck struct {
+	rng *rand.Rand
+}
+
+func (s seededDeck) Arrange(deck *game.Deck, _ []byte) error {
+	seed := make([]byte, 8)
+	binary.LittleEndian.PutUint64(seed, s.rng.Uint64())
+	deck.Reset()
+	deck.ShuffleWithSeed(seed)
+	return nil
+}
+
+type simulator struct {
+	game     *game.Game
+	rng      *rand.Rand
+	bots     map[string]*ai.Bot
+	stack    int64
+	timeouts float64
+	actions  []string
+}
+
+func main() {
+	hands := flag.Int("hands", 1000000, "hands to play")
+	seed := flag.Int64("seed", 1, "seed for cards, actions and bots")
+	players := flag.Int("players", 6, "players at the table")
+	mode := flag.String("mode", "random", "how players act: random (any legal action) or bot")
+	variant := flag.String("variant", "holdem", "game variant")
+	structure := flag.String("structure", "no_limit", "betting structure")
+	ante := flag.Int64("ante", 0, "ante per player")
+	stack := flag.Int64("stack", 1000, "starting stack, also used to rebuy busted players")
+	timeouts := flag.Float64("timeouts", 0.01, "chance a player lets the clock run out")
+	flag.Parse()
+
+	if *mode != "random" && *mode != "bot" {
+		log.Fatalf("unknown mode: %s", *mode)
+	}
+	config := game.DefaultConfig()
+	config.Variant = *variant
+	config.Ante = *ante
+	config.MaxPlayers = *players
+	bettingStructure, err := game.ParseBettingStructure(*structure)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.BettingStructure = bettingStructure
+	if _, err := game.VariantByName(*variant); err != nil {
+		log.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	sim := &simulator{
+		game:     game.NewGameWithDeck("simulate", config, seededDeck{rng: rng}),
+		rng:      rng,
+		stack:    *stack,
+		timeouts: *timeouts,
+	}
+	for i := 0; i < *players; i++ {
+		id := fmt.Sprintf("p%d", i)
+		if err := sim.game.AddPlayer(game.NewPlayer(id, id, *stack)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "bot" {
+		sim.bots = make(map[string]*ai.Bot)
+		for i, p := range sim.game.Players {
+			sim.bots[p.ID] = ai.NewSeededBot(p.ID, ai.Difficulty(i%4), rng.Int63())
+		}
+	}
+
+	start := time.Now()
+	for n := 1; n <= *hands; n++ {
+		if err := sim.playHand(); err != nil {
+			fmt.Fprintf(os.Stderr, "hand %d: %v\n", n, err)
+			sim.dump(os.Stderr)
+			fmt.Fprintf(os.Stderr, "reproduce with: %s\n", reproduceCommand(n))
+			os.Exit(1)
+		}
+		if n%100000 == 0 {
+			log.Printf("%d hands in %v", n, time.Since(start).Round(time.Second))
+		}
+	}
+	fmt.Printf("%d hands played in %v, no invariant violated\n", *hands, time.Since(start).Round(time.Millisecond))
+}
+
+func (s *simulator) playHand() error {
+	g := s.game
+	s.actions = s.actions[:0]
+
+	for _, p := range g.Players {
+		if p.Chips == 0 {
+			if err := g.AddChips(p.ID, s.stack); err != nil {
+				return fmt.Errorf("rebuy %s: %v", p.ID, err)
+			}
+			s.record("%s rebuys %d", p.ID, s.stack)
+		}
+	}
+
+	if err := g.StartHand(); err != nil {
+		return fmt.Errorf("start hand: %v", err)
+	}
+	if err := s.check("start"); err != nil {
+		return err
+	}
+
+	for g.IsBettingRound() || g.Phase == game.PhaseDiscard {
+		if g.Phase == game.PhaseDiscard {
+			if err := s.discard(); err != nil {
+				return err
+			}
+			if err := s.check(s.actions[len(s.actions)-1]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		p := g.GetCurrentPlayer()
+		if p == nil {
+			return fmt.Errorf("betting round with nobody to act")
+		}
+
+		var err error
+		switch {
+		case s.rng.Float64() < s.timeouts:
+			err = s.expire(p)
+		case s.bots != nil:
+			err = s.botAction(p)
+		default:
+			err = s.randomAction(p)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.check(s.actions[len(s.actions)-1]); err != nil {
+			return err
+		}
+	}
+	return s.check("end")
+}
+
+func (s *simulator) expire(p *game.Player) error {
+	result := s.game.ExpireAction(s.game.ActionDeadline)
+	if result == nil {
+		return fmt.Errorf("%s did not time out at the deadline", p.ID)
+	}
+	if result.TimeBankUsed > 0 {
+		s.record("%s times out, uses %ds of time bank", p.ID, result.TimeBankUsed)
+	} else {
+		s.record("%s times out, %s", p.ID, result.Action)
+	}
+	return nil
+}
+
+// randomAction picks any legal action, so a refusal is itself a bug.
+// Folding is made rarer to get more hands to showdown.
+func (s *simulator) randomAction(p *game.Player) error {
+	legal, _ := s.game.LegalActions(p.ID)
+	if len(legal) < 2 {
+		return fmt.Errorf("%s is to act but may only %v", p.ID, legal)
+	}
+	a := legal[s.rng.Intn(len(legal))]
+	if a.Action == game.ActionFold && s.rng.Intn(2) == 0 {
+		a = legal[1+s.rng.Intn(len(legal)-1)]
+	}
+	amount := a.Min
+	if a.Max > a.Min {
+		amount += s.rng.Int63n(a.Max - a.Min + 1)
+	}
+
+	s.record("%s %s %d", p.ID, a.Action, amount)
+	if err := s.game.ProcessAction(p.ID, a.Action, amount); err != nil {
+		return fmt.Errorf("legal action refused: %v", err)
+	}
+	return nil
+}
+
+// botAction lets the bot decide. Bots sometimes ask for what they may not
+// do, in which case they check or call instead.
+func (s *simulator) botAction(p *game.Player) error {
+	d := s.bots[p.ID].MakeDecision(s.game, p)
+	s.record("%s %s %d (%s)", p.ID, d.Action, d.Amount, d.Reason)
+	err := s.game.ProcessAction(p.ID, d.Action, d.Amount)
+	if err == nil {
+		return nil
+	}
+
+	legal, _ := s.game.LegalActions(p.ID)
+	if len(legal) < 2 {
+		return fmt.Errorf("%s is to act but may only %v", p.ID, legal)
+	}
+	s.record("  refused (%v), %s instead", err, legal[1].Action)
+	if err := s.game.ProcessAction(p.ID, legal[1].Action, legal[1].Min); err != nil {
+		return fmt.Errorf("legal action refused: %v", err)
+	}
+	return nil
+}
+
+// discard throws away a random card for the first player still holding
+// one too many, or lets the discard clock run out on everyone.
+func (s *simulator) discard() error {
+	if s.rng.Float64() < s.timeouts {
+		if !s.game.ExpireDiscards(s.game.ActionDeadline) {
+			return fmt.Errorf("discard did not time out at the deadline")
+		}
+		s.record("discard times out")
+		return nil
+	}
+
+	for _, p := range s.game.Players {
+		legal, _ := s.game.LegalActions(p.ID)
+		if len(legal) != 1 || legal[0].Action != game.ActionDiscard {
+			continue
+		}
+		index := s.rng.Int63n(legal[0].Max + 1)
+		s.record("%s discards card %d", p.ID, index)
+		if err := s.game.ProcessAction(p.ID, game.ActionDiscard, index); err != nil {
+			return fmt.Errorf("legal discard refused: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("discard with nobody left to discard")
+}
+
+func (s *simulator) check(step string) error {
+	if err := s.game.CheckInvariants(); err != nil {
+		return fmt.Errorf("after %s: %v", step, err)
+	}
+	return nil
+}
+
+func (s *simulator) record(format string, args ...interface{}) {
+	s.actions = append(s.actions, fmt.Sprintf(format, args...))
+}
+
+func (s *simulator) dump(w io.Writer) {
+	fmt.Fprintln(w, "actions:")
+	for _, a := range s.actions {
+		fmt.Fprintf(w, "  %s\n", a)
+	}
+	fmt.Fprintln(w, "events:")
+	for _, e := range s.game.Events(0) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			fmt.Fprintf(w, "  seq %d: %v\n", e.Seq, err)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", data)
+	}
+}
+
+// reproduceCommand repeats the flags given, stopping at the failing hand.
+func reproduceCommand(hand int) string {
+	cmd := "go run ./cmd/simulate"
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "hands" {
+			cmd += fmt.Sprintf(" -%s=%s", f.Name, f.Value)
+		}
+	})
+	return fmt.Sprintf("%s -hands=%d", cmd, hand)
+}
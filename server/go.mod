@@ -10,3 +10,5 @@ require (
 	github.com/golang-jwt/jwt/v5 v5.1.0
 	golang.org/x/crypto v0.15.0
 )
+
+require golang.org/x/net v0.17.0 // indirect
@@ -36,6 +36,15 @@ func NewBot(playerID string, difficulty Difficulty) *Bot {
 	}
 }
 
+// NewSeededBot makes a bot whose choices are reproducible from seed.
+func NewSeededBot(playerID string, difficulty Difficulty, seed int64) *Bot {
+	return &Bot{
+		PlayerID:   playerID,
+		Difficulty: difficulty,
+		rng:        rand.New(rand.NewSource(seed)),
+	}
+}
+
 func (b *Bot) MakeDecision(g *game.Game, player *game.Player) Decision {
 	switch b.Difficulty {
 	case Easy:
@@ -74,7 +83,7 @@ func (b *Bot) makeEasyDecision(g *game.Game, player *game.Player) Decision {
 }
 
 func (b *Bot) makeMediumDecision(g *game.Game, player *game.Player) Decision {
-	handStrength := b.evaluateHandStrength(player.HoleCards, g.CommunityCards)
+	handStrength := b.evaluateHandStrength(g.Variant(), player.HoleCards, g.CommunityCards)
 	callAmount := g.CurrentBet - player.CurrentBet
 	potOdds := b.calculatePotOdds(g, callAmount)
 
@@ -104,7 +113,7 @@ func (b *Bot) makeMediumDecision(g *game.Game, player *game.Player) Decision {
 }
 
 func (b *Bot) makeHardDecision(g *game.Game, player *game.Player) Decision {
-	handStrength := b.evaluateHandStrength(player.HoleCards, g.CommunityCards)
+	handStrength := b.evaluateHandStrength(g.Variant(), player.HoleCards, g.CommunityCards)
 	positionValue := b.getPositionValue(g, player)
 	adjustedStrength := handStrength * (0.8 + positionValue*0.4)
 
@@ -165,19 +174,67 @@ func (b *Bot) makeExpertDecision(g *game.Game, player *game.Player) Decision {
 	return decision
 }
 
-func (b *Bot) evaluateHandStrength(holeCards, communityCards []game.Card) float64 {
+type strengthTable struct {
+	lowRank   int
+	handTypes map[game.HandRankType]float64
+}
+
+var standardStrengths = strengthTable{
+	lowRank: int(game.Two),
+	handTypes: map[game.HandRankType]float64{
+		game.RoyalFlush:    0.98,
+		game.StraightFlush: 0.98,
+		game.FourOfAKind:   0.95,
+		game.FullHouse:     0.90,
+		game.Flush:         0.80,
+		game.Straight:      0.70,
+		game.ThreeOfAKind:  0.60,
+		game.TwoPair:       0.50,
+		game.OnePair:       0.35,
+		game.HighCard:      0.15,
+	},
+}
+
+// Short deck starts at Six, so pairs and straights come far more often and
+// a flush outranks a full house.
+var shortDeckStrengths = strengthTable{
+	lowRank: int(game.Six),
+	handTypes: map[game.HandRankType]float64{
+		game.RoyalFlush:    0.98,
+		game.StraightFlush: 0.98,
+		game.FourOfAKind:   0.95,
+		game.Flush:         0.90,
+		game.FullHouse:     0.82,
+		game.Straight:      0.65,
+		game.ThreeOfAKind:  0.60,
+		game.TwoPair:       0.45,
+		game.OnePair:       0.28,
+		game.HighCard:      0.10,
+	},
+}
+
+func strengthTableFor(variant game.Variant) strengthTable {
+	if variant == game.ShortDeck {
+		return shortDeckStrengths
+	}
+	return standardStrengths
+}
+
+func (b *Bot) evaluateHandStrength(variant game.Variant, holeCards, communityCards []game.Card) float64 {
 	if len(holeCards) < 2 {
 		return 0.2
 	}
 
+	table := strengthTableFor(variant)
+
 	if len(communityCards) == 0 {
-		return b.evaluatePreflopStrength(holeCards)
+		return b.evaluatePreflopStrength(table, holeCards)
 	}
 
-	return b.evaluatePostflopStrength(holeCards, communityCards)
+	return b.evaluatePostflopStrength(variant, table, holeCards, communityCards)
 }
 
-func (b *Bot) evaluatePreflopStrength(holeCards []game.Card) float64 {
+func (b *Bot) evaluatePreflopStrength(table strengthTable, holeCards []game.Card) float64 {
 	rank1 := int(holeCards[0].Rank)
 	rank2 := int(holeCards[1].Rank)
 	isPair := rank1 == rank2
@@ -190,10 +247,12 @@ func (b *Bot) evaluatePreflopStrength(holeCards []game.Card) float64 {
 		lowRank = rank1
 	}
 
+	span := float64(int(game.Ace) - table.lowRank)
+
 	var strength float64
 
 	if isPair {
-		strength = 0.5 + float64(highRank-2)/24.0
+		strength = 0.5 + float64(highRank-table.lowRank)/(2*span)
 		if highRank >= 10 {
 			strength += 0.1
 		}
@@ -201,7 +260,7 @@ func (b *Bot) evaluatePreflopStrength(holeCards []game.Card) float64 {
 			strength = 0.95
 		}
 	} else {
-		strength = (float64(highRank-2) + float64(lowRank-2)*0.5) / 30.0
+		strength = (float64(highRank-table.lowRank) + float64(lowRank-table.lowRank)*0.5) / (2.5 * span)
 
 		if highRank == int(game.Ace) && lowRank >= 10 {
 			strength = 0.75
@@ -230,33 +289,13 @@ func (b *Bot) evaluatePreflopStrength(holeCards []game.Card) float64 {
 	return strength
 }
 
-func (b *Bot) evaluatePostflopStrength(holeCards, communityCards []game.Card) float64 {
-	handRank := game.EvaluateHand(holeCards, communityCards)
-
-	var baseStrength float64
-	switch handRank.Type {
-	case game.RoyalFlush, game.StraightFlush:
-		baseStrength = 0.98
-	case game.FourOfAKind:
-		baseStrength = 0.95
-	case game.FullHouse:
-		baseStrength = 0.90
-	case game.Flush:
-		baseStrength = 0.80
-	case game.Straight:
-		baseStrength = 0.70
-	case game.ThreeOfAKind:
-		baseStrength = 0.60
-	case game.TwoPair:
-		baseStrength = 0.50
-	case game.OnePair:
-		baseStrength = 0.35
-	default:
-		baseStrength = 0.15
-	}
+func (b *Bot) evaluatePostflopStrength(variant game.Variant, table strengthTable, holeCards, communityCards []game.Card) float64 {
+	handRank := variant.EvaluateHand(holeCards, communityCards)
+
+	baseStrength := table.handTypes[handRank.Type]
 
 	if len(handRank.Kickers) > 0 {
-		baseStrength += float64(handRank.Kickers[0]-2) / 100.0
+		baseStrength += float64(int(handRank.Kickers[0])-table.lowRank) / 100.0
 	}
 
 	if baseStrength > 1 {
@@ -271,7 +310,7 @@ func (b *Bot) getPositionValue(g *game.Game, player *game.Player) float64 {
 	playerIndex := 0
 	dealerIndex := 0
 
-	for i, p := range g.Players {
+	for _, p := range g.Players {
 		if p.State == game.StateActive || p.State == game.StateAllIn {
 			if p.ID == player.ID {
 				playerIndex = activePlayers
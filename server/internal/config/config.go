@@ -11,6 +11,7 @@ type Config struct {
 	DatabaseURL   string
 	JWTSecret     string
 	Environment   string
+	SnapshotPath  string // live rooms are saved here on shutdown
 	
 	// Game settings
 	DefaultSmallBlind int64
@@ -30,6 +31,7 @@ func Load() *Config {
 		DatabaseURL:        getEnv("DATABASE_URL", "postgres://localhost:5432/texas_holdem"),
 		JWTSecret:          getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 		Environment:        getEnv("ENVIRONMENT", "development"),
+		SnapshotPath:       getEnv("SNAPSHOT_PATH", "data/rooms.snapshot.json"),
 		DefaultSmallBlind:  getEnvInt64("DEFAULT_SMALL_BLIND", 10),
 		DefaultBigBlind:    getEnvInt64("DEFAULT_BIG_BLIND", 20),
 		MaxPlayersPerRoom:  getEnvInt("MAX_PLAYERS_PER_ROOM", 9),
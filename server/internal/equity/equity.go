@@ -0,0 +1,261 @@
+package equity
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"texas-holdem-server/internal/game"
+)
+
+const (
+	DefaultIterations = 100000
+	// MaxExactOutcomes caps how many showdowns are enumerated before the
+	// calculator falls back to Monte Carlo.
+	MaxExactOutcomes = 2000000
+	maxPlayers       = 9
+)
+
+// Options tunes a calculation. Zero values use the defaults.
+type Options struct {
+	Iterations  int   `json:"iterations"`
+	Seed        int64 `json:"seed"`
+	ForceSample bool  `json:"-"`
+}
+
+type PlayerEquity struct {
+	Equity float64 `json:"equity"` // share of the pot, ties split
+	Win    float64 `json:"win"`
+	Tie    float64 `json:"tie"`
+	Combos int     `json:"combos"` // combos left after removing known cards
+}
+
+type Result struct {
+	Players []PlayerEquity `json:"players"`
+	Samples int            `json:"samples"`
+	Exact   bool           `json:"exact"`
+}
+
+// Calculate returns the Hold'em equity of each range on board, which may
+// hold zero to five cards. Cards in dead are out of play. Small problems are
+// enumerated exactly, larger ones sampled.
+func Calculate(ranges [][]Combo, board, dead []game.Card, opts Options) (*Result, error) {
+	if len(ranges) < 2 || len(ranges) > maxPlayers {
+		return nil, fmt.Errorf("need between 2 and %d hands", maxPlayers)
+	}
+	if len(board) > 5 {
+		return nil, fmt.Errorf("board has more than five cards")
+	}
+
+	known := make(map[game.Card]bool)
+	for _, c := range append(append([]game.Card{}, board...), dead...) {
+		if known[c] {
+			return nil, fmt.Errorf("card %s used twice", c)
+		}
+		known[c] = true
+	}
+	// Every hand takes two cards out of the deck before the board is dealt.
+	if 52-len(known)-2*len(ranges) < 5-len(board) {
+		return nil, fmt.Errorf("not enough cards left to deal the board")
+	}
+
+	live := make([][]Combo, len(ranges))
+	for i, r := range ranges {
+		for _, combo := range r {
+			if !known[combo[0]] && !known[combo[1]] {
+				live[i] = append(live[i], combo)
+			}
+		}
+		if len(live[i]) == 0 {
+			return nil, fmt.Errorf("hand %d has no combos left", i+1)
+		}
+	}
+
+	c := &calculator{
+		ranges: live,
+		board:  board,
+		known:  known,
+		wins:   make([]float64, len(live)),
+		ties:   make([]float64, len(live)),
+		shares: make([]float64, len(live)),
+		hands:  make([]Combo, len(live)),
+		values: make([]game.HandValue, len(live)),
+	}
+
+	exact := !opts.ForceSample && c.outcomeBound() <= MaxExactOutcomes
+	if exact {
+		c.enumerate(0, make(map[game.Card]bool))
+	} else {
+		iterations := opts.Iterations
+		if iterations <= 0 {
+			iterations = DefaultIterations
+		}
+		seed := opts.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		c.sample(rand.New(rand.NewSource(seed)), iterations)
+	}
+
+	if c.samples == 0 {
+		return nil, fmt.Errorf("ranges cannot all be dealt together")
+	}
+
+	result := &Result{
+		Players: make([]PlayerEquity, len(live)),
+		Samples: c.samples,
+		Exact:   exact,
+	}
+	n := float64(c.samples)
+	for i := range live {
+		result.Players[i] = PlayerEquity{
+			Equity: c.shares[i] / n,
+			Win:    c.wins[i] / n,
+			Tie:    c.ties[i] / n,
+			Combos: len(live[i]),
+		}
+	}
+	return result, nil
+}
+
+type calculator struct {
+	ranges [][]Combo
+	board  []game.Card
+	known  map[game.Card]bool
+
+	hands   []Combo
+	values  []game.HandValue
+	cards   [7]game.Card
+	wins    []float64
+	ties    []float64
+	shares  []float64
+	samples int
+}
+
+// outcomeBound over-estimates the showdowns an exact run would visit.
+func (c *calculator) outcomeBound() float64 {
+	bound := 1.0
+	for _, r := range c.ranges {
+		bound *= float64(len(r))
+	}
+	remaining := 52 - len(c.known) - 2*len(c.ranges)
+	for i := 0; i < 5-len(c.board); i++ {
+		bound *= float64(remaining-i) / float64(i+1)
+	}
+	return bound
+}
+
+func (c *calculator) enumerate(player int, used map[game.Card]bool) {
+	if player == len(c.ranges) {
+		c.enumerateBoards(used)
+		return
+	}
+	for _, combo := range c.ranges[player] {
+		if used[combo[0]] || used[combo[1]] {
+			continue
+		}
+		used[combo[0]], used[combo[1]] = true, true
+		c.hands[player] = combo
+		c.enumerate(player+1, used)
+		delete(used, combo[0])
+		delete(used, combo[1])
+	}
+}
+
+func (c *calculator) enumerateBoards(used map[game.Card]bool) {
+	stub := make([]game.Card, 0, 52)
+	for i := 0; i < 52; i++ {
+		card := game.CardFromIndex(i)
+		if !c.known[card] && !used[card] {
+			stub = append(stub, card)
+		}
+	}
+
+	board := append(make([]game.Card, 0, 5), c.board...)
+	var deal func(start int)
+	deal = func(start int) {
+		if len(board) == 5 {
+			c.showdown(board)
+			return
+		}
+		for i := start; i < len(stub); i++ {
+			board = append(board, stub[i])
+			deal(i + 1)
+			board = board[:len(board)-1]
+		}
+	}
+	deal(0)
+}
+
+func (c *calculator) sample(rng *rand.Rand, iterations int) {
+	stub := make([]game.Card, 0, 52)
+	for i := 0; i < 52; i++ {
+		if card := game.CardFromIndex(i); !c.known[card] {
+			stub = append(stub, card)
+		}
+	}
+
+	used := make(map[game.Card]bool)
+	board := make([]game.Card, 0, 5)
+	// Give up on ranges that almost never fit together rather than spin.
+	for attempts := 0; c.samples < iterations && attempts < iterations*20; attempts++ {
+		for card := range used {
+			delete(used, card)
+		}
+		ok := true
+		for i, r := range c.ranges {
+			combo := r[rng.Intn(len(r))]
+			if used[combo[0]] || used[combo[1]] {
+				ok = false
+				break
+			}
+			used[combo[0]], used[combo[1]] = true, true
+			c.hands[i] = combo
+		}
+		if !ok {
+			continue
+		}
+
+		board = append(board[:0], c.board...)
+		for len(board) < 5 {
+			card := stub[rng.Intn(len(stub))]
+			if used[card] {
+				continue
+			}
+			used[card] = true
+			board = append(board, card)
+		}
+		c.showdown(board)
+	}
+}
+
+func (c *calculator) showdown(board []game.Card) {
+	copy(c.cards[2:], board)
+	var best game.HandValue
+	winners := 0
+	for i, combo := range c.hands {
+		c.cards[0], c.cards[1] = combo[0], combo[1]
+		v := game.Evaluate(c.cards[:])
+		c.values[i] = v
+		if v > best {
+			best = v
+			winners = 1
+		} else if v == best {
+			winners++
+		}
+	}
+
+	share := 1 / float64(winners)
+	for i, v := range c.values {
+		if v != best {
+			continue
+		}
+		c.shares[i] += share
+		if winners == 1 {
+			c.wins[i]++
+		} else {
+			c.ties[i]++
+		}
+	}
+	c.samples++
+}
@@ -0,0 +1,96 @@
+package equity
+
+import (
+	"math"
+	"testing"
+
+	"texas-holdem-server/internal/game"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := map[string]int{
+		"AA":          6,
+		"TT+":         30,
+		"AKs":         4,
+		"KQo":         12,
+		"AK":          16,
+		"ATs+":        16,
+		"A2s-A5s":     16,
+		"22-44":       18,
+		"AhKd":        1,
+		"TT+, AKs":    34,
+		"QQ+ KK AhKh": 19,
+	}
+	for notation, want := range cases {
+		combos, err := ParseRange(notation)
+		if err != nil {
+			t.Errorf("ParseRange(%q) failed: %v", notation, err)
+			continue
+		}
+		if len(combos) != want {
+			t.Errorf("Expected %d combos for %q, got %d", want, notation, len(combos))
+		}
+	}
+
+	for _, bad := range []string{"", "AAs", "AX", "AKs-QJs", "AhAh"} {
+		if _, err := ParseRange(bad); err == nil {
+			t.Errorf("ParseRange(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCalculateExactOnTurn(t *testing.T) {
+	hero, _ := ParseRange("AhKh")
+	villain, _ := ParseRange("QsQd")
+	board, _ := ParseCards("2h7hQc3s")
+
+	result, err := Calculate([][]Combo{hero, villain}, board, nil, Options{})
+	if err != nil {
+		t.Fatalf("Calculate failed: %v", err)
+	}
+	if !result.Exact || result.Samples != 44 {
+		t.Errorf("Expected an exact run over 44 rivers, got exact=%v samples=%d", result.Exact, result.Samples)
+	}
+	// Nine hearts are left, but 3h fills the set up and Qh makes quads.
+	if math.Abs(result.Players[0].Equity-7.0/44) > 1e-9 {
+		t.Errorf("Expected equity 7/44, got %f", result.Players[0].Equity)
+	}
+}
+
+func TestCalculateRejectsTooFewCardsForTheBoard(t *testing.T) {
+	hero, _ := ParseRange("AhKh")
+	villain, _ := ParseRange("QsQd")
+	used := map[game.Card]bool{hero[0][0]: true, hero[0][1]: true, villain[0][0]: true, villain[0][1]: true}
+
+	// Leave four live cards, one short of a board.
+	var dead []game.Card
+	for _, c := range game.NewDeck().Cards() {
+		if !used[c] && len(dead) < 44 {
+			dead = append(dead, c)
+		}
+	}
+
+	if _, err := Calculate([][]Combo{hero, villain}, nil, dead, Options{Iterations: 10, Seed: 1, ForceSample: true}); err == nil {
+		t.Errorf("Expected an error with only four cards left for the board")
+	}
+}
+
+func TestCalculateMonteCarloWithDeadCards(t *testing.T) {
+	aces, _ := ParseRange("AA")
+	kings, _ := ParseRange("KK")
+	dead, _ := ParseCards("As Ad")
+
+	result, err := Calculate([][]Combo{aces, kings}, nil, dead, Options{Iterations: 20000, Seed: 1, ForceSample: true})
+	if err != nil {
+		t.Fatalf("Calculate failed: %v", err)
+	}
+	if result.Exact || result.Players[0].Combos != 1 {
+		t.Errorf("Expected a sampled run with one aces combo left, got exact=%v combos=%d", result.Exact, result.Players[0].Combos)
+	}
+	if eq := result.Players[0].Equity; eq < 0.78 || eq > 0.86 {
+		t.Errorf("Expected aces to hold about 82%%, got %f", eq)
+	}
+	if sum := result.Players[0].Equity + result.Players[1].Equity; math.Abs(sum-1) > 1e-9 {
+		t.Errorf("Equities should add up to 1, got %f", sum)
+	}
+}
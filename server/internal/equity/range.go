@@ -0,0 +1,204 @@
+package equity
+
+import (
+	"fmt"
+	"strings"
+
+	"texas-holdem-server/internal/game"
+)
+
+// Combo is one specific pair of hole cards.
+type Combo [2]game.Card
+
+func (c Combo) String() string {
+	return c[0].String() + c[1].String()
+}
+
+const rankChars = "23456789TJQKA"
+
+// ParseRange reads a hand range in the usual notation, separated by commas
+// or spaces:
+//
+//	AA, TT+, 22-55        pairs
+//	AKs, AKo, AK          suited, offsuit or both
+//	ATs+, A2s-A5s         kicker ranges
+//	AhKd                  one exact combo
+func ParseRange(s string) ([]Combo, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty range")
+	}
+
+	seen := make(map[Combo]bool)
+	combos := make([]Combo, 0)
+	for _, field := range fields {
+		parsed, err := parseRangePart(field)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range parsed {
+			if !seen[c] {
+				seen[c] = true
+				combos = append(combos, c)
+			}
+		}
+	}
+	return combos, nil
+}
+
+func parseRangePart(part string) ([]Combo, error) {
+	if len(part) == 4 && strings.IndexByte("hdcs", part[1]) >= 0 {
+		return parseExactCombo(part)
+	}
+
+	if i := strings.IndexByte(part, '-'); i >= 0 {
+		return parseSpan(part[:i], part[i+1:])
+	}
+
+	plus := strings.HasSuffix(part, "+")
+	hand, err := parseHandClass(strings.TrimSuffix(part, "+"))
+	if err != nil {
+		return nil, err
+	}
+	if !plus {
+		return hand.combos(), nil
+	}
+
+	// TT+ climbs to AA; ATs+ climbs the kicker up to AKs.
+	top := game.Ace
+	if !hand.pair() {
+		top = hand.high - 1
+	}
+	return hand.through(top), nil
+}
+
+func parseExactCombo(part string) ([]Combo, error) {
+	a, err := game.ParseCard(part[:2])
+	if err != nil {
+		return nil, err
+	}
+	b, err := game.ParseCard(part[2:])
+	if err != nil {
+		return nil, err
+	}
+	if a == b {
+		return nil, fmt.Errorf("invalid combo: %s", part)
+	}
+	return []Combo{{a, b}}, nil
+}
+
+func parseSpan(from, to string) ([]Combo, error) {
+	a, err := parseHandClass(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := parseHandClass(to)
+	if err != nil {
+		return nil, err
+	}
+	if a.pair() != b.pair() || a.suited != b.suited || (!a.pair() && a.high != b.high) {
+		return nil, fmt.Errorf("invalid range: %s-%s", from, to)
+	}
+
+	if a.low > b.low {
+		a, b = b, a
+	}
+	return a.through(b.low), nil
+}
+
+// handClass is a starting hand such as QQ, AKs or T9o. Neither suited nor
+// offsuit means both.
+type handClass struct {
+	high, low game.Rank
+	suited    bool
+	offsuit   bool
+}
+
+func parseHandClass(s string) (handClass, error) {
+	if len(s) < 2 || len(s) > 3 {
+		return handClass{}, fmt.Errorf("invalid hand: %s", s)
+	}
+	hi := strings.IndexByte(rankChars, strings.ToUpper(s[:1])[0])
+	lo := strings.IndexByte(rankChars, strings.ToUpper(s[1:2])[0])
+	if hi < 0 || lo < 0 {
+		return handClass{}, fmt.Errorf("invalid hand: %s", s)
+	}
+	if hi < lo {
+		hi, lo = lo, hi
+	}
+
+	h := handClass{high: game.Rank(hi + 2), low: game.Rank(lo + 2)}
+	if len(s) == 3 {
+		switch strings.ToLower(s[2:]) {
+		case "s":
+			h.suited = true
+		case "o":
+			h.offsuit = true
+		default:
+			return handClass{}, fmt.Errorf("invalid hand: %s", s)
+		}
+		if h.pair() {
+			return handClass{}, fmt.Errorf("invalid hand: %s", s)
+		}
+	}
+	return h, nil
+}
+
+func (h handClass) pair() bool {
+	return h.high == h.low
+}
+
+// through returns every combo from h up to the class whose lower card is
+// top, moving both cards for pairs and only the kicker otherwise.
+func (h handClass) through(top game.Rank) []Combo {
+	combos := make([]Combo, 0)
+	for r := h.low; r <= top; r++ {
+		next := h
+		next.low = r
+		if h.pair() {
+			next.high = r
+		}
+		combos = append(combos, next.combos()...)
+	}
+	return combos
+}
+
+func (h handClass) combos() []Combo {
+	combos := make([]Combo, 0, 12)
+	for s1 := game.Hearts; s1 <= game.Spades; s1++ {
+		for s2 := game.Hearts; s2 <= game.Spades; s2++ {
+			if h.pair() {
+				if s2 <= s1 {
+					continue
+				}
+			} else if (h.suited && s1 != s2) || (h.offsuit && s1 == s2) {
+				continue
+			}
+			combos = append(combos, Combo{game.NewCard(s1, h.high), game.NewCard(s2, h.low)})
+		}
+	}
+	return combos
+}
+
+// ParseCards reads cards written back to back or space separated, such as
+// "Ks7d2c" or "Ks 7d 2c".
+func ParseCards(s string) ([]game.Card, error) {
+	s = strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	}), "")
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("invalid cards: %s", s)
+	}
+
+	cards := make([]game.Card, 0, len(s)/2)
+	for i := 0; i < len(s); i += 2 {
+		c, err := game.ParseCard(s[i : i+2])
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
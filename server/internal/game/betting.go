@@ -0,0 +1,183 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+type BettingStructure int
+
+const (
+	NoLimit BettingStructure = iota
+	PotLimit
+	FixedLimit
+)
+
+func (b BettingStructure) String() string {
+	names := []string{"no_limit", "pot_limit", "fixed_limit"}
+	return names[b]
+}
+
+func ParseBettingStructure(s string) (BettingStructure, error) {
+	switch s {
+	case "", "no_limit", "nl":
+		return NoLimit, nil
+	case "pot_limit", "pl":
+		return PotLimit, nil
+	case "fixed_limit", "limit", "fl":
+		return FixedLimit, nil
+	default:
+		return NoLimit, fmt.Errorf("unknown betting structure: %s", s)
+	}
+}
+
+// smallBet and bigBet are the fixed-limit bet sizes: the small bet applies
+// preflop and on the flop, the big bet on the turn and river.
+func (g *Game) smallBet() int64 {
+	if g.Config.SmallBet > 0 {
+		return g.Config.SmallBet
+	}
+	return g.Config.BigBlind
+}
+
+func (g *Game) bigBet() int64 {
+	if g.Config.BigBet > 0 {
+		return g.Config.BigBet
+	}
+	return 2 * g.smallBet()
+}
+
+func (g *Game) streetMinRaise() int64 {
+	if g.Config.BettingStructure != FixedLimit {
+		return g.Config.BigBlind
+	}
+	if g.Phase >= PhaseTurn {
+		return g.bigBet()
+	}
+	return g.smallBet()
+}
+
+func (g *Game) raiseCapReached() bool {
+	return g.Config.BettingStructure == FixedLimit &&
+		g.Config.RaiseCap > 0 &&
+		g.RaisesThisStreet >= g.Config.RaiseCap
+}
+
+// raiseBounds returns the smallest and largest total a player may raise to
+// under the table's betting structure. ok is false when the player cannot
+// raise at all: the street is capped, their stack does not exceed the
+// current bet, or only a short all-in has come in since they acted.
+func (g *Game) raiseBounds(p *Player) (minTotal, maxTotal int64, ok bool) {
+	stackTotal := p.Chips + p.CurrentBet
+	if stackTotal <= g.CurrentBet || g.raiseCapReached() || !g.actionReopened(p) {
+		return 0, 0, false
+	}
+
+	switch g.Config.BettingStructure {
+	case PotLimit:
+		minTotal = g.CurrentBet + g.MinRaise
+		maxTotal = g.CurrentBet + g.potAfterCall(p)
+	case FixedLimit:
+		minTotal = g.CurrentBet + g.streetMinRaise()
+		maxTotal = minTotal
+	default:
+		minTotal = g.CurrentBet + g.MinRaise
+		maxTotal = stackTotal
+	}
+
+	if maxTotal > stackTotal {
+		maxTotal = stackTotal
+	}
+	if minTotal > maxTotal {
+		minTotal = maxTotal
+	}
+	return minTotal, maxTotal, true
+}
+
+// isFullRaise reports whether raising the bet by amount reopens the action.
+// In fixed limit half a bet is enough, as under TDA rules.
+func (g *Game) isFullRaise(amount int64) bool {
+	if g.Config.BettingStructure == FixedLimit {
+		return 2*amount >= g.streetMinRaise()
+	}
+	return amount >= g.MinRaise
+}
+
+// actionReopened reports whether p may raise. Anyone yet to act may; a
+// player who has acted may again only once the bet has gone up by a full
+// raise since, counting several short all-ins together.
+func (g *Game) actionReopened(p *Player) bool {
+	if !hasActed(p) {
+		return true
+	}
+	return g.isFullRaise(g.CurrentBet - p.CurrentBet)
+}
+
+func hasActed(p *Player) bool {
+	switch p.LastAction {
+	case ActionNone, ActionSmallBlind, ActionBigBlind, ActionStraddle:
+		return false
+	}
+	return true
+}
+
+// potAfterCall is the pot-limit raise size: everything committed so far,
+// including bets still on the table, plus the amount the player must call.
+func (g *Game) potAfterCall(p *Player) int64 {
+	total := g.DeadMoney
+	for _, other := range g.Players {
+		total += other.TotalBetInHand
+	}
+	if toCall := g.CurrentBet - p.CurrentBet; toCall > 0 {
+		total += toCall
+	}
+	return total
+}
+
+// LegalAction is one thing a player may do on their turn. For a raise Min
+// and Max bound the total to raise to; for a call or all-in both are the
+// chips the action puts in.
+type LegalAction struct {
+	Action ActionType `json:"action"`
+	Min    int64      `json:"min"`
+	Max    int64      `json:"max"`
+}
+
+// LegalActions lists what playerID may do right now and when their time
+// runs out. It returns no actions when it is not their turn. During a
+// discard the only action is the discard, with Min and Max bounding the
+// index of the card to throw away.
+func (g *Game) LegalActions(playerID string) ([]LegalAction, time.Time) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	p := g.getPlayerByID(playerID)
+	if p != nil && g.Phase == PhaseDiscard && g.mustDiscard(p) {
+		return []LegalAction{{Action: ActionDiscard, Max: int64(len(p.HoleCards) - 1)}}, g.ActionDeadline
+	}
+	if p == nil || p.State != StateActive || p.SeatIndex != g.CurrentPlayerSeat ||
+		g.RunItOffer != nil || !g.isBettingRoundLocked() {
+		return nil, time.Time{}
+	}
+
+	actions := []LegalAction{{Action: ActionFold}}
+	toCall := g.CurrentBet - p.CurrentBet
+	if toCall <= 0 {
+		actions = append(actions, LegalAction{Action: ActionCheck})
+	} else {
+		call := min(toCall, p.Chips)
+		actions = append(actions, LegalAction{Action: ActionCall, Min: call, Max: call})
+	}
+
+	stackTotal := p.Chips + p.CurrentBet
+	minTotal, maxTotal, canRaise := g.raiseBounds(p)
+	if canRaise {
+		actions = append(actions, LegalAction{Action: ActionRaise, Min: minTotal, Max: maxTotal})
+	}
+	// Going all in is a call when the stack does not cover the bet, and
+	// otherwise a raise that has to fit the betting structure.
+	if stackTotal <= g.CurrentBet || (canRaise && stackTotal <= maxTotal) {
+		actions = append(actions, LegalAction{Action: ActionAllIn, Min: p.Chips, Max: p.Chips})
+	}
+	return actions, g.ActionDeadline
+}
@@ -0,0 +1,278 @@
+package game
+
+import (
+	"fmt"
+)
+
+type AnteMode int
+
+const (
+	// AntePerPlayer takes Config.Ante from everyone dealt in.
+	AntePerPlayer AnteMode = iota
+	// AnteBigBlind has the big blind pay one ante for the whole table.
+	AnteBigBlind
+	// AnteButton has the button pay one ante for the whole table.
+	AnteButton
+)
+
+func (a AnteMode) String() string {
+	names := []string{"per_player", "big_blind", "button"}
+	return names[a]
+}
+
+func ParseAnteMode(s string) (AnteMode, error) {
+	switch s {
+	case "", "per_player":
+		return AntePerPlayer, nil
+	case "big_blind", "bb":
+		return AnteBigBlind, nil
+	case "button":
+		return AnteButton, nil
+	default:
+		return AntePerPlayer, fmt.Errorf("unknown ante mode: %s", s)
+	}
+}
+
+type StraddleMode int
+
+const (
+	StraddleNone StraddleMode = iota
+	// StraddleUTG lets the player left of the big blind straddle.
+	StraddleUTG
+	// StraddleButton lets the button straddle; preflop action then starts
+	// with the small blind.
+	StraddleButton
+)
+
+func (s StraddleMode) String() string {
+	names := []string{"none", "utg", "button"}
+	return names[s]
+}
+
+func ParseStraddleMode(s string) (StraddleMode, error) {
+	switch s {
+	case "", "none":
+		return StraddleNone, nil
+	case "utg":
+		return StraddleUTG, nil
+	case "button":
+		return StraddleButton, nil
+	default:
+		return StraddleNone, fmt.Errorf("unknown straddle mode: %s", s)
+	}
+}
+
+// SetStraddle opts a player in or out of straddling whenever they are in
+// the table's straddle position.
+func (g *Game) SetStraddle(playerID string, enabled bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Config.Straddle == StraddleNone {
+		return fmt.Errorf("straddles are not allowed at this table")
+	}
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	player.Straddle = enabled
+	return nil
+}
+
+// SitOut takes a player out of the deal. A player still in a hand keeps
+// playing it and sits out from the next one.
+func (g *Game) SitOut(playerID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	if player.State == StateActive || player.State == StateAllIn {
+		player.sitOutNextHand = true
+		return nil
+	}
+	player.State = StateSittingOut
+	return nil
+}
+
+// SitIn deals a player back in from the next hand. Blinds they missed are
+// collected when that hand starts.
+func (g *Game) SitIn(playerID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	player.sitOutNextHand = false
+	if player.State == StateSittingOut {
+		player.State = StateWaiting
+	}
+	return nil
+}
+
+// nextPlayerAfter returns the first player clockwise from seat, not counting
+// seat itself until last, that matches the filter.
+func (g *Game) nextPlayerAfter(seat int, match func(p *Player) bool) *Player {
+	seats := g.seatCount()
+	var next *Player
+	best := seats
+	for _, p := range g.Players {
+		if !match(p) {
+			continue
+		}
+		if d := (p.SeatIndex - seat - 1 + 2*seats) % seats; d < best {
+			best = d
+			next = p
+		}
+	}
+	return next
+}
+
+// NextBigBlind is the player due to post the big blind next hand, or ""
+// if nobody is.
+func (g *Game) NextBigBlind() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	p := g.nextPlayerAfter(g.BigBlindSeat, func(p *Player) bool {
+		return p.Chips > 0 && p.State != StateSittingOut
+	})
+	if p == nil {
+		return ""
+	}
+	return p.ID
+}
+
+func (g *Game) seatCount() int {
+	seats := g.Config.MaxPlayers
+	for _, p := range g.Players {
+		if p.SeatIndex >= seats {
+			seats = p.SeatIndex + 1
+		}
+	}
+	return seats
+}
+
+func isDealtIn(p *Player) bool {
+	return p.State == StateActive || p.State == StateAllIn
+}
+
+// moveBlindsDeadButton moves the big blind to the next player dealt in and
+// lets the small blind and button follow it seat by seat, so nobody skips
+// a blind when players bust or sit out. The small blind or the button may
+// land on an empty seat. Sitting-out players the big blind passes owe the
+// blinds they missed.
+func (g *Game) moveBlindsDeadButton() {
+	prevSB, prevBB := g.SmallBlindSeat, g.BigBlindSeat
+	next := g.nextPlayerAfter(prevBB, isDealtIn)
+
+	seats := g.seatCount()
+	for _, p := range g.Players {
+		if p.State != StateSittingOut {
+			continue
+		}
+		passed := (p.SeatIndex - prevBB - 1 + seats) % seats
+		if passed < (next.SeatIndex-prevBB-1+seats)%seats {
+			p.MissedBigBlind = true
+			p.MissedSmallBlind = true
+		} else if p.SeatIndex == prevBB {
+			p.MissedSmallBlind = true
+		}
+	}
+
+	g.DealerSeat = prevSB
+	g.SmallBlindSeat = prevBB
+	g.BigBlindSeat = next.SeatIndex
+}
+
+// straddleSeat is the seat of the player straddling this hand, or -1.
+func (g *Game) straddleSeat() int {
+	if g.Config.Straddle == StraddleNone || g.Config.BettingStructure == FixedLimit {
+		return -1
+	}
+	if len(g.getActivePlayers()) < 3 {
+		return -1
+	}
+
+	var p *Player
+	switch g.Config.Straddle {
+	case StraddleUTG:
+		p = g.nextPlayerAfter(g.BigBlindSeat, isDealtIn)
+	case StraddleButton:
+		p = g.getPlayerBySeat(g.DealerSeat)
+	}
+	if p == nil || !p.Straddle || p.State != StateActive || p.CurrentBet > 0 || p.Chips <= 2*g.Config.BigBlind {
+		return -1
+	}
+	return p.SeatIndex
+}
+
+// postAnte takes an ante that counts toward the player's share of the pot
+// but not toward the bet they have to match.
+func (g *Game) postAnte(p *Player, amount int64) {
+	actual := min(amount, p.Chips)
+	if actual <= 0 {
+		return
+	}
+	p.Chips -= actual
+	p.TotalBetInHand += actual
+	if p.Chips == 0 {
+		p.State = StateAllIn
+	}
+	g.emit(BlindPosted{PlayerID: p.ID, SeatIndex: p.SeatIndex, Kind: BlindAnte, Amount: actual})
+}
+
+// postBlind puts a live forced bet in front of the player.
+func (g *Game) postBlind(p *Player, kind BlindKind, amount int64) {
+	actual := p.PlaceBet(amount)
+	g.emit(BlindPosted{PlayerID: p.ID, SeatIndex: p.SeatIndex, Kind: kind, Amount: actual})
+}
+
+func (g *Game) postAntes() {
+	ante := g.Config.Ante
+	switch g.Config.AnteMode {
+	case AnteBigBlind, AnteButton:
+		if ante <= 0 {
+			ante = g.Config.BigBlind
+		}
+		seat := g.BigBlindSeat
+		if g.Config.AnteMode == AnteButton {
+			seat = g.DealerSeat
+		}
+		if p := g.getPlayerBySeat(seat); p != nil && isDealtIn(p) {
+			g.postDeadMoney(p, BlindAnte, ante)
+		}
+	default:
+		if ante <= 0 {
+			return
+		}
+		for _, p := range g.getActivePlayers() {
+			g.postAnte(p, ante)
+		}
+	}
+}
+
+// collectMissedBlinds makes returning players pay what they skipped: the
+// big blind live, so they get their option, and the small blind dead.
+func (g *Game) collectMissedBlinds() {
+	for _, p := range g.getActivePlayers() {
+		if p.SeatIndex == g.BigBlindSeat || p.SeatIndex == g.SmallBlindSeat {
+			p.MissedBigBlind = false
+			p.MissedSmallBlind = false
+			continue
+		}
+		if p.MissedBigBlind {
+			p.LastAction = ActionBigBlind
+			g.postBlind(p, BlindMissedBig, g.Config.BigBlind)
+		}
+		if p.MissedSmallBlind {
+			g.postDeadMoney(p, BlindMissedSmall, g.Config.SmallBlind)
+		}
+		p.MissedBigBlind = false
+		p.MissedSmallBlind = false
+	}
+}
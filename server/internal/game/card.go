@@ -1,10 +1,10 @@
 package game
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
 	"sort"
-	"time"
+	"strings"
 )
 
 type Suit int
@@ -59,6 +59,20 @@ func (c Card) String() string {
 	return fmt.Sprintf("%c%c", ranks[c.Rank-2], suits[c.Suit])
 }
 
+// ParseCard reads a card in the form produced by String, such as "Ah" or
+// "Td".
+func ParseCard(s string) (Card, error) {
+	if len(s) != 2 {
+		return Card{}, fmt.Errorf("invalid card: %s", s)
+	}
+	rank := strings.IndexByte("23456789TJQKA", strings.ToUpper(s[:1])[0])
+	suit := strings.IndexByte("hdcs", strings.ToLower(s[1:])[0])
+	if rank < 0 || suit < 0 {
+		return Card{}, fmt.Errorf("invalid card: %s", s)
+	}
+	return NewCard(Suit(suit), Rank(rank+2)), nil
+}
+
 func (c Card) SuitSymbol() string {
 	symbols := []string{"♥", "♦", "♣", "♠"}
 	return symbols[c.Suit]
@@ -71,31 +85,83 @@ func (c Card) RankString() string {
 
 type Deck struct {
 	cards []Card
+	full  []Card
 	index int
-	rng   *rand.Rand
 }
 
 func NewDeck() *Deck {
+	cards := make([]Card, 52)
+	for i := range cards {
+		cards[i] = CardFromIndex(i)
+	}
+	return newDeckFrom(cards)
+}
+
+// NewShortDeck builds the 36-card deck used by short-deck Hold'em, with
+// every Two through Five removed.
+func NewShortDeck() *Deck {
+	cards := make([]Card, 0, 36)
+	for i := 0; i < 52; i++ {
+		if c := CardFromIndex(i); c.Rank >= Six {
+			cards = append(cards, c)
+		}
+	}
+	return newDeckFrom(cards)
+}
+
+func newDeckFrom(cards []Card) *Deck {
 	d := &Deck{
-		cards: make([]Card, 52),
-		rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		cards: make([]Card, len(cards)),
+		full:  cards,
 	}
 	d.Reset()
 	return d
 }
 
 func (d *Deck) Reset() {
-	for i := 0; i < 52; i++ {
-		d.cards[i] = CardFromIndex(i)
-	}
+	copy(d.cards, d.full)
 	d.index = 0
 }
 
 func (d *Deck) Shuffle() {
+	seed := make([]byte, serverSeedBytes)
+	if _, err := rand.Read(seed); err != nil {
+		panic(err)
+	}
+	d.ShuffleWithSeed(seed)
+}
+
+// ShuffleWithSeed runs a Fisher-Yates shuffle driven by seed, so the same
+// seed always produces the same order.
+func (d *Deck) ShuffleWithSeed(seed []byte) {
 	d.index = 0
-	d.rng.Shuffle(len(d.cards), func(i, j int) {
+	stream := newSeedStream(seed)
+	for i := len(d.cards) - 1; i > 0; i-- {
+		j := stream.intn(i + 1)
 		d.cards[i], d.cards[j] = d.cards[j], d.cards[i]
-	})
+	}
+}
+
+// Cards returns the whole deck in dealing order.
+func (d *Deck) Cards() []Card {
+	return append([]Card{}, d.cards...)
+}
+
+// Stack resets the deck and moves top to the front in the given order.
+func (d *Deck) Stack(top []Card) error {
+	d.Reset()
+	for i, c := range top {
+		j := i
+		for j < len(d.cards) && d.cards[j] != c {
+			j++
+		}
+		if j == len(d.cards) {
+			return fmt.Errorf("card %s not in deck or stacked twice", c)
+		}
+		copy(d.cards[i+1:j+1], d.cards[i:j])
+		d.cards[i] = c
+	}
+	return nil
 }
 
 func (d *Deck) Deal() (Card, error) {
@@ -159,11 +225,13 @@ type HandRank struct {
 	Type    HandRankType `json:"type"`
 	Kickers []Rank       `json:"kickers"`
 	Cards   []Card       `json:"cards"`
+
+	shortDeck bool
 }
 
 func (h HandRank) Compare(other HandRank) int {
 	if h.Type != other.Type {
-		if h.Type > other.Type {
+		if h.typeOrder() > other.typeOrder() {
 			return 1
 		}
 		return -1
@@ -181,20 +249,44 @@ func (h HandRank) Compare(other HandRank) int {
 	return 0
 }
 
+// typeOrder is the strength of the hand category. With fewer cards per
+// suit a flush is rarer than a full house in short deck, so the two swap.
+func (h HandRank) typeOrder() int {
+	if h.shortDeck {
+		switch h.Type {
+		case Flush:
+			return int(FullHouse)
+		case FullHouse:
+			return int(Flush)
+		}
+	}
+	return int(h.Type)
+}
+
 func EvaluateHand(holeCards, communityCards []Card) HandRank {
+	return evaluateBestHand(holeCards, communityCards, false)
+}
+
+// EvaluateShortDeckHand scores a hand under short-deck rules: A-6-7-8-9 is
+// the lowest straight and a flush beats a full house.
+func EvaluateShortDeckHand(holeCards, communityCards []Card) HandRank {
+	return evaluateBestHand(holeCards, communityCards, true)
+}
+
+func evaluateBestHand(holeCards, communityCards []Card, shortDeck bool) HandRank {
 	allCards := append([]Card{}, holeCards...)
 	allCards = append(allCards, communityCards...)
 
 	if len(allCards) < 5 {
-		return HandRank{Type: HighCard}
+		return HandRank{Type: HighCard, shortDeck: shortDeck}
 	}
 
 	var bestHand HandRank
 	combinations := getCombinations(allCards, 5)
 
-	for _, combo := range combinations {
-		hand := evaluateFiveCards(combo)
-		if hand.Compare(bestHand) > 0 {
+	for i, combo := range combinations {
+		hand := evaluateFiveCards(combo, shortDeck)
+		if i == 0 || hand.Compare(bestHand) > 0 {
 			bestHand = hand
 		}
 	}
@@ -202,13 +294,19 @@ func EvaluateHand(holeCards, communityCards []Card) HandRank {
 	return bestHand
 }
 
-func evaluateFiveCards(cards []Card) HandRank {
+func evaluateFiveCards(cards []Card, shortDeck bool) HandRank {
+	hand := evaluateFive(cards, shortDeck)
+	hand.shortDeck = shortDeck
+	return hand
+}
+
+func evaluateFive(cards []Card, shortDeck bool) HandRank {
 	sort.Slice(cards, func(i, j int) bool {
 		return cards[i].Rank > cards[j].Rank
 	})
 
 	isFlush := checkFlush(cards)
-	isStraight, highCard := checkStraight(cards)
+	isStraight, highCard := checkStraight(cards, shortDeck)
 	groups := getRankGroups(cards)
 
 	if isFlush && isStraight {
@@ -287,15 +385,19 @@ func checkFlush(cards []Card) bool {
 	return true
 }
 
-func checkStraight(cards []Card) (bool, Rank) {
+func checkStraight(cards []Card, shortDeck bool) (bool, Rank) {
 	ranks := make([]int, len(cards))
 	for i, c := range cards {
 		ranks[i] = int(c.Rank)
 	}
 	sort.Sort(sort.Reverse(sort.IntSlice(ranks)))
 
-	// Check A-2-3-4-5
-	if ranks[0] == int(Ace) && ranks[1] == 5 && ranks[2] == 4 && ranks[3] == 3 && ranks[4] == 2 {
+	// Check A-2-3-4-5, or A-6-7-8-9 without the low cards
+	if shortDeck {
+		if ranks[0] == int(Ace) && ranks[1] == 9 && ranks[2] == 8 && ranks[3] == 7 && ranks[4] == 6 {
+			return true, Nine
+		}
+	} else if ranks[0] == int(Ace) && ranks[1] == 5 && ranks[2] == 4 && ranks[3] == 3 && ranks[4] == 2 {
 		return true, Five
 	}
 
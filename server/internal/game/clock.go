@@ -0,0 +1,147 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+type TimeoutResult struct {
+	PlayerID     string     `json:"playerId"`
+	SeatIndex    int        `json:"seatIndex"`
+	Action       ActionType `json:"action"`
+	TimeBankUsed int        `json:"timeBankUsed"`
+	TimeBank     int        `json:"timeBank"`
+	Deadline     time.Time  `json:"deadline"`
+}
+
+func (g *Game) IsBettingRound() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.isBettingRoundLocked()
+}
+
+func (g *Game) isBettingRoundLocked() bool {
+	return g.Phase >= PhasePreflop && g.Phase <= PhaseRiver
+}
+
+// UseTimeBank spends one chunk of the acting player's time bank and pushes
+// the action deadline back by that amount.
+func (g *Game) UseTimeBank(playerID string) (*TimeoutResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return nil, fmt.Errorf("player not found")
+	}
+	if !g.isBettingRoundLocked() || player.SeatIndex != g.CurrentPlayerSeat {
+		return nil, fmt.Errorf("not your turn")
+	}
+	if player.TimeBank <= 0 {
+		return nil, fmt.Errorf("time bank exhausted")
+	}
+
+	return g.spendTimeBank(player, time.Now()), nil
+}
+
+// ExpireAction enforces the action clock. Once the deadline has passed the
+// acting player's time bank is drawn on first; with nothing left they are
+// checked if possible and folded otherwise. Returns nil while the clock is
+// still running.
+func (g *Game) ExpireAction(now time.Time) *TimeoutResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.isBettingRoundLocked() || now.Before(g.ActionDeadline) {
+		return nil
+	}
+
+	player := g.getPlayerBySeat(g.CurrentPlayerSeat)
+	if player == nil || player.State != StateActive {
+		return nil
+	}
+
+	if player.TimeBank > 0 {
+		return g.spendTimeBank(player, now)
+	}
+
+	action := ActionFold
+	if player.CurrentBet >= g.CurrentBet {
+		action = ActionCheck
+	}
+
+	result := &TimeoutResult{
+		PlayerID:  player.ID,
+		SeatIndex: player.SeatIndex,
+		Action:    action,
+	}
+	if err := g.processActionLocked(player.ID, action, 0); err != nil {
+		return nil
+	}
+	return result
+}
+
+func (g *Game) spendTimeBank(player *Player, now time.Time) *TimeoutResult {
+	chunk := g.Config.TimeBankChunk
+	if chunk <= 0 || chunk > player.TimeBank {
+		chunk = player.TimeBank
+	}
+	player.TimeBank -= chunk
+
+	if g.ActionDeadline.Before(now) {
+		g.ActionDeadline = now
+	}
+	g.ActionDeadline = g.ActionDeadline.Add(time.Duration(chunk) * time.Second)
+	g.emit(TimeBankUsed{
+		PlayerID:  player.ID,
+		SeatIndex: player.SeatIndex,
+		Seconds:   chunk,
+		TimeBank:  player.TimeBank,
+		Deadline:  g.ActionDeadline,
+	})
+
+	return &TimeoutResult{
+		PlayerID:     player.ID,
+		SeatIndex:    player.SeatIndex,
+		TimeBankUsed: chunk,
+		TimeBank:     player.TimeBank,
+		Deadline:     g.ActionDeadline,
+	}
+}
+
+// RestartClock gives whoever is to act a full clock from now, for a game
+// restored after downtime nobody could act in.
+func (g *Game) RestartClock(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.RunItOffer != nil {
+		g.RunItOffer.Deadline = now.Add(time.Duration(g.Config.RunItTimeout) * time.Second)
+		g.ActionDeadline = g.RunItOffer.Deadline
+		return
+	}
+	if g.Phase == PhaseDiscard || (g.isBettingRoundLocked() && g.CurrentPlayerSeat >= 0) {
+		g.ActionDeadline = now.Add(time.Duration(g.Config.ActionTimeout) * time.Second)
+	}
+}
+
+func (g *Game) refillTimeBanks() {
+	if g.Config.TimeBankRefillHands <= 0 || g.HandNumber%g.Config.TimeBankRefillHands != 0 {
+		return
+	}
+	for _, p := range g.Players {
+		p.TimeBank += g.Config.TimeBankRefill
+		if p.TimeBank > g.Config.TimeBank {
+			p.TimeBank = g.Config.TimeBank
+		}
+	}
+}
+
+func (g *Game) getPlayerBySeat(seat int) *Player {
+	for _, p := range g.Players {
+		if p.SeatIndex == seat {
+			return p
+		}
+	}
+	return nil
+}
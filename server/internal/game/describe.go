@@ -0,0 +1,143 @@
+package game
+
+import (
+	"strings"
+)
+
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleChinese Locale = "zh"
+)
+
+// Locales are the languages hands can be described in.
+var Locales = []Locale{LocaleEnglish, LocaleChinese}
+
+// ParseLocale picks the closest supported language, falling back to
+// English: "zh-CN" and "zh_TW" both describe hands in Chinese.
+func ParseLocale(s string) Locale {
+	s = strings.ToLower(s)
+	if s == "zh" || strings.HasPrefix(s, "zh-") || strings.HasPrefix(s, "zh_") {
+		return LocaleChinese
+	}
+	return LocaleEnglish
+}
+
+var (
+	rankNamesEn = map[Rank]string{
+		Two: "Two", Three: "Three", Four: "Four", Five: "Five", Six: "Six",
+		Seven: "Seven", Eight: "Eight", Nine: "Nine", Ten: "Ten",
+		Jack: "Jack", Queen: "Queen", King: "King", Ace: "Ace",
+	}
+	rankPluralsEn = map[Rank]string{
+		Two: "Twos", Three: "Threes", Four: "Fours", Five: "Fives", Six: "Sixes",
+		Seven: "Sevens", Eight: "Eights", Nine: "Nines", Ten: "Tens",
+		Jack: "Jacks", Queen: "Queens", King: "Kings", Ace: "Aces",
+	}
+	rankNamesZh = map[Rank]string{
+		Two: "2", Three: "3", Four: "4", Five: "5", Six: "6",
+		Seven: "7", Eight: "8", Nine: "9", Ten: "10",
+		Jack: "J", Queen: "Q", King: "K", Ace: "A",
+	}
+	typeNamesZh = []string{
+		"高牌", "一对", "两对", "三条", "顺子",
+		"同花", "葫芦", "四条", "同花顺", "皇家同花顺",
+	}
+)
+
+// Describe names the hand along with every rank that decides a tie, such
+// as "Two Pair, Kings and Nines, Ace kicker" or "Straight, Five high".
+func (h HandRank) Describe(locale Locale) string {
+	if locale == LocaleChinese {
+		return h.describeZh()
+	}
+	return h.describeEn()
+}
+
+func (h HandRank) String() string {
+	return h.Describe(LocaleEnglish)
+}
+
+// Descriptions describes the hand in every supported language.
+func (h HandRank) Descriptions() map[Locale]string {
+	d := make(map[Locale]string, len(Locales))
+	for _, l := range Locales {
+		d[l] = h.Describe(l)
+	}
+	return d
+}
+
+func (h HandRank) describeEn() string {
+	k := h.Kickers
+	name := h.Type.String()
+	if h.Type == RoyalFlush || len(k) < expectedKickers[h.Type] {
+		return name
+	}
+
+	switch h.Type {
+	case OnePair:
+		return name + ", " + rankPluralsEn[k[0]] + kickersEn(k[1:])
+	case TwoPair:
+		return name + ", " + rankPluralsEn[k[0]] + " and " + rankPluralsEn[k[1]] + kickersEn(k[2:])
+	case ThreeOfAKind, FourOfAKind:
+		return name + ", " + rankPluralsEn[k[0]] + kickersEn(k[1:])
+	case FullHouse:
+		return name + ", " + rankPluralsEn[k[0]] + " full of " + rankPluralsEn[k[1]]
+	case Straight, StraightFlush:
+		return name + ", " + rankNamesEn[k[0]] + " high"
+	default:
+		return name + ", " + joinRanks(k, rankNamesEn)
+	}
+}
+
+func (h HandRank) describeZh() string {
+	k := h.Kickers
+	name := typeNamesZh[h.Type]
+	if h.Type == RoyalFlush || len(k) < expectedKickers[h.Type] {
+		return name
+	}
+
+	switch h.Type {
+	case OnePair, ThreeOfAKind, FourOfAKind:
+		return name + " " + rankNamesZh[k[0]] + kickersZh(k[1:])
+	case TwoPair:
+		return name + "，" + rankNamesZh[k[0]] + " 和 " + rankNamesZh[k[1]] + kickersZh(k[2:])
+	case FullHouse:
+		return name + "，" + rankNamesZh[k[0]] + " 带 " + rankNamesZh[k[1]]
+	case Straight, StraightFlush:
+		return name + "，" + rankNamesZh[k[0]] + " 高"
+	default:
+		return name + "，" + joinRanks(k, rankNamesZh)
+	}
+}
+
+// expectedKickers is how many ranks a hand of each type carries; a hand
+// made from fewer than five cards has none and is described by type alone.
+var expectedKickers = []int{5, 4, 3, 3, 1, 5, 2, 2, 1, 1}
+
+func kickersEn(k []Rank) string {
+	switch len(k) {
+	case 0:
+		return ""
+	case 1:
+		return ", " + rankNamesEn[k[0]] + " kicker"
+	default:
+		return ", " + joinRanks(k, rankNamesEn) + " kickers"
+	}
+}
+
+func kickersZh(k []Rank) string {
+	if len(k) == 0 {
+		return ""
+	}
+	return "，踢脚 " + joinRanks(k, rankNamesZh)
+}
+
+func joinRanks(ranks []Rank, names map[Rank]string) string {
+	parts := make([]string, len(ranks))
+	for i, r := range ranks {
+		parts[i] = names[r]
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// Discard throws away one of a player's hole cards, by its index in their
+// hand, during a Pineapple discard. The next street is dealt once the last
+// player still holding an extra card has thrown it away.
+func (g *Game) Discard(playerID string, index int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.discardLocked(playerID, index)
+}
+
+func (g *Game) discardLocked(playerID string, index int) error {
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	if g.Phase != PhaseDiscard {
+		return fmt.Errorf("not time to discard")
+	}
+	if !g.mustDiscard(player) {
+		return fmt.Errorf("nothing to discard")
+	}
+	if index < 0 || index >= len(player.HoleCards) {
+		return fmt.Errorf("invalid card")
+	}
+
+	g.discard(player, index)
+	return nil
+}
+
+// ExpireDiscards throws away a card for everyone who has not discarded by
+// the deadline. Reports whether it did.
+func (g *Game) ExpireDiscards(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Phase != PhaseDiscard || now.Before(g.ActionDeadline) {
+		return false
+	}
+	g.discardForPending(func(*Player) bool { return true })
+	return true
+}
+
+// startDiscard opens the discard once the street it follows has been bet.
+// Nobody acts in turn: everyone still in the hand discards at once, bots
+// straight away.
+func (g *Game) startDiscard() {
+	g.Phase = PhaseDiscard
+	g.CurrentPlayerSeat = -1
+	g.ActionDeadline = time.Now().Add(time.Duration(g.Config.ActionTimeout) * time.Second)
+	g.emit(DiscardStarted{Deadline: g.ActionDeadline})
+
+	g.discardForPending(func(p *Player) bool { return p.IsBot })
+}
+
+func (g *Game) discardForPending(which func(*Player) bool) {
+	for _, p := range g.getActivePlayers() {
+		if g.Phase == PhaseDiscard && g.mustDiscard(p) && which(p) {
+			g.discard(p, g.autoDiscard(p))
+		}
+	}
+}
+
+func (g *Game) discard(p *Player, index int) {
+	card := p.HoleCards[index]
+	p.HoleCards = withoutCard(p.HoleCards, index)
+	g.emit(CardDiscarded{PlayerID: p.ID, SeatIndex: p.SeatIndex, Card: card})
+
+	if g.closeDiscard() {
+		g.dealNextStreet()
+	}
+}
+
+// closeDiscard returns the game to the street just bet once nobody has a
+// card left to throw away, gathering the pots again in case someone left
+// meanwhile. Reports whether it did.
+func (g *Game) closeDiscard() bool {
+	if g.discardPending() {
+		return false
+	}
+	g.Phase = g.variant.DiscardAfter()
+	g.collectBets()
+	return true
+}
+
+// MustDiscard reports whether playerID still has a card to throw away.
+func (g *Game) MustDiscard(playerID string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	p := g.getPlayerByID(playerID)
+	return p != nil && g.Phase == PhaseDiscard && g.mustDiscard(p)
+}
+
+// mustDiscard reports whether a player still in the hand holds the extra
+// card a discard takes away.
+func (g *Game) mustDiscard(p *Player) bool {
+	return g.variant.DiscardAfter() != PhaseWaiting &&
+		isContending(p) && len(p.HoleCards) >= g.variant.HoleCardCount()
+}
+
+func (g *Game) discardPending() bool {
+	for _, p := range g.Players {
+		if g.mustDiscard(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// autoDiscard picks the card to throw away for a player who did not choose:
+// on the flop the one whose loss leaves the best hand, before it the
+// lowest card that does not break up a pair.
+func (g *Game) autoDiscard(p *Player) int {
+	cards := p.HoleCards
+	if len(g.CommunityCards) >= 3 {
+		best := 0
+		var bestHand HandRank
+		for i := range cards {
+			hand := g.variant.EvaluateHand(withoutCard(cards, i), g.CommunityCards)
+			if i == 0 || hand.Compare(bestHand) > 0 {
+				best, bestHand = i, hand
+			}
+		}
+		return best
+	}
+
+	lowest := -1
+	for i, c := range cards {
+		paired := false
+		for j, other := range cards {
+			if i != j && c.Rank == other.Rank {
+				paired = true
+				break
+			}
+		}
+		if !paired && (lowest < 0 || c.Rank < cards[lowest].Rank) {
+			lowest = i
+		}
+	}
+	if lowest < 0 {
+		return len(cards) - 1
+	}
+	return lowest
+}
+
+func withoutCard(cards []Card, index int) []Card {
+	return append(append(make([]Card, 0, len(cards)-1), cards[:index]...), cards[index+1:]...)
+}
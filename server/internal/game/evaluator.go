@@ -0,0 +1,145 @@
+package game
+
+import (
+	"math/bits"
+)
+
+// HandValue is the strength of a hand packed into one integer: the category
+// in the top bits and up to five ranks below it, most significant first, so
+// a larger value is always a better hand. It follows standard rankings.
+type HandValue uint32
+
+const (
+	valueTypeShift = 20
+	rankMaskSize   = 1 << 13
+)
+
+var (
+	// straightHigh holds the top card of the best straight in a rank mask,
+	// or zero when there is none.
+	straightHigh [rankMaskSize]Rank
+	// topFive packs the five highest ranks of a rank mask, four bits each.
+	topFive [rankMaskSize]uint32
+)
+
+func init() {
+	for mask := 0; mask < rankMaskSize; mask++ {
+		for high := Ace; high >= Six; high-- {
+			run := 0x1F << (high - Six)
+			if mask&run == run {
+				straightHigh[mask] = high
+				break
+			}
+		}
+		if straightHigh[mask] == 0 && mask&0x100F == 0x100F {
+			straightHigh[mask] = Five
+		}
+
+		var packed uint32
+		n := 0
+		for r := Ace; r >= Two && n < 5; r-- {
+			if mask&(1<<(r-Two)) != 0 {
+				packed |= uint32(r) << (4 * (4 - n))
+				n++
+			}
+		}
+		topFive[mask] = packed
+	}
+}
+
+func (v HandValue) Type() HandRankType {
+	return HandRankType(v >> valueTypeShift)
+}
+
+func newHandValue(t HandRankType, ranks uint32) HandValue {
+	return HandValue(uint32(t)<<valueTypeShift | ranks)
+}
+
+// Evaluate scores the best five-card hand among five to seven cards without
+// allocating. Use EvaluateHand when the cards making the hand are needed.
+func Evaluate(cards []Card) HandValue {
+	var suits [4]uint16
+	var counts [Ace + 1]uint8
+	for _, c := range cards {
+		suits[c.Suit] |= 1 << (c.Rank - Two)
+		counts[c.Rank]++
+	}
+
+	// With seven cards or fewer a flush rules out quads and a full house.
+	for _, mask := range suits {
+		if bits.OnesCount16(mask) < 5 {
+			continue
+		}
+		if high := straightHigh[mask]; high != 0 {
+			if high == Ace {
+				return newHandValue(RoyalFlush, uint32(Ace)<<16)
+			}
+			return newHandValue(StraightFlush, uint32(high)<<16)
+		}
+		return newHandValue(Flush, topFive[mask])
+	}
+
+	ranks := suits[0] | suits[1] | suits[2] | suits[3]
+
+	var quad, trip, secondTrip Rank
+	var pairs [3]Rank
+	numPairs := 0
+	for r := Ace; r >= Two; r-- {
+		switch counts[r] {
+		case 4:
+			quad = r
+		case 3:
+			if trip == 0 {
+				trip = r
+			} else if secondTrip == 0 {
+				secondTrip = r
+			}
+		case 2:
+			if numPairs < len(pairs) {
+				pairs[numPairs] = r
+				numPairs++
+			}
+		}
+	}
+
+	without := func(rs ...Rank) uint32 {
+		mask := ranks
+		for _, r := range rs {
+			mask &^= 1 << (r - Two)
+		}
+		return topFive[mask]
+	}
+
+	switch {
+	case quad != 0:
+		return newHandValue(FourOfAKind, uint32(quad)<<16|without(quad)>>16<<12)
+	case trip != 0 && (secondTrip != 0 || numPairs > 0):
+		pair := secondTrip
+		if pairs[0] > pair {
+			pair = pairs[0]
+		}
+		return newHandValue(FullHouse, uint32(trip)<<16|uint32(pair)<<12)
+	case straightHigh[ranks] != 0:
+		return newHandValue(Straight, uint32(straightHigh[ranks])<<16)
+	case trip != 0:
+		return newHandValue(ThreeOfAKind, uint32(trip)<<16|without(trip)>>12<<8)
+	case numPairs >= 2:
+		return newHandValue(TwoPair, uint32(pairs[0])<<16|uint32(pairs[1])<<12|without(pairs[0], pairs[1])>>16<<8)
+	case numPairs == 1:
+		return newHandValue(OnePair, uint32(pairs[0])<<16|without(pairs[0])>>8<<4)
+	default:
+		return newHandValue(HighCard, topFive[ranks])
+	}
+}
+
+// value packs a HandRank the same way Evaluate does.
+func (h HandRank) value() HandValue {
+	var ranks uint32
+	for i, k := range h.Kickers {
+		if i == 5 {
+			break
+		}
+		ranks |= uint32(k) << (4 * (4 - i))
+	}
+	return newHandValue(h.Type, ranks)
+}
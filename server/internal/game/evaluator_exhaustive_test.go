@@ -0,0 +1,116 @@
+//go:build exhaustive
+
+package game
+
+import "testing"
+
+// TestEvaluateAllSevenCardHands checks Evaluate against EvaluateHand on
+// every one of the 133,784,560 seven-card hands, and the categories against
+// the known totals. It takes a few minutes, so it is only built with the
+// exhaustive tag:
+//
+//	go test -tags exhaustive -timeout 0 -run AllSevenCard ./internal/game
+//
+// EvaluateHand is the best evaluateFiveCards of the 21 five-card hands in
+// seven cards. Calling it 133 million times would take hours, so the old
+// evaluator's value of every five-card hand is worked out once and each
+// seven-card hand takes the best of its 21. Every 1024th hand is checked
+// against EvaluateHand itself as well.
+func TestEvaluateAllSevenCardHands(t *testing.T) {
+	want := map[HandRankType]int{
+		RoyalFlush:    4324,
+		StraightFlush: 37260,
+		FourOfAKind:   224848,
+		FullHouse:     3473184,
+		Flush:         4047644,
+		Straight:      6180020,
+		ThreeOfAKind:  6461620,
+		TwoPair:       31433400,
+		OnePair:       58627800,
+		HighCard:      23294460,
+	}
+
+	// choose[n][k] numbers the k-card subsets of the deck: the subset
+	// c1 < c2 < ... < ck is at choose[c1][1] + choose[c2][2] + ... + choose[ck][k].
+	var choose [53][6]int
+	for n := 0; n <= 52; n++ {
+		choose[n][0] = 1
+		for k := 1; k <= 5 && k <= n; k++ {
+			choose[n][k] = choose[n-1][k-1] + choose[n-1][k]
+		}
+	}
+
+	deck := NewDeck().Cards()
+	fives := make([]HandValue, choose[52][5])
+	five := make([]Card, 5)
+	for a := 0; a < 52; a++ {
+		for b := a + 1; b < 52; b++ {
+			for c := b + 1; c < 52; c++ {
+				for d := c + 1; d < 52; d++ {
+					for e := d + 1; e < 52; e++ {
+						five[0], five[1], five[2], five[3], five[4] = deck[a], deck[b], deck[c], deck[d], deck[e]
+						index := choose[a][1] + choose[b][2] + choose[c][3] + choose[d][4] + choose[e][5]
+						fives[index] = evaluateFiveCards(five, false).value()
+					}
+				}
+			}
+		}
+	}
+
+	got := make(map[HandRankType]int)
+	hand := make([]Card, 7)
+	var idx [7]int
+	hands := 0
+	for idx[0] = 0; idx[0] < 52; idx[0]++ {
+		for idx[1] = idx[0] + 1; idx[1] < 52; idx[1]++ {
+			for idx[2] = idx[1] + 1; idx[2] < 52; idx[2]++ {
+				for idx[3] = idx[2] + 1; idx[3] < 52; idx[3]++ {
+					for idx[4] = idx[3] + 1; idx[4] < 52; idx[4]++ {
+						for idx[5] = idx[4] + 1; idx[5] < 52; idx[5]++ {
+							for idx[6] = idx[5] + 1; idx[6] < 52; idx[6]++ {
+								for i, c := range idx {
+									hand[i] = deck[c]
+								}
+
+								// The best five, leaving out two cards each time.
+								var best HandValue
+								for x := 0; x < 7; x++ {
+									for y := x + 1; y < 7; y++ {
+										index, k := 0, 1
+										for i, c := range idx {
+											if i != x && i != y {
+												index += choose[c][k]
+												k++
+											}
+										}
+										if fives[index] > best {
+											best = fives[index]
+										}
+									}
+								}
+
+								value := Evaluate(hand)
+								if value != best {
+									t.Fatalf("Evaluate(%v) = %x, want %x", hand, value, best)
+								}
+								if hands%1024 == 0 {
+									if old := EvaluateHand(hand[:2], hand[2:]).value(); value != old {
+										t.Fatalf("Evaluate(%v) = %x, EvaluateHand gives %x", hand, value, old)
+									}
+								}
+								got[value.Type()]++
+								hands++
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for handType, count := range want {
+		if got[handType] != count {
+			t.Errorf("Expected %d %s hands, got %d", count, handType, got[handType])
+		}
+	}
+}
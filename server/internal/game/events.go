@@ -0,0 +1,646 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventHandStarted    EventType = "hand_started"
+	EventBlindPosted    EventType = "blind_posted"
+	EventHoleCardsDealt EventType = "hole_cards_dealt"
+	EventActionTaken    EventType = "action_taken"
+	EventStreetDealt    EventType = "street_dealt"
+	EventRunItOffered   EventType = "run_it_offered"
+	EventBetReturned    EventType = "bet_returned"
+	EventCardsShown     EventType = "cards_shown"
+	EventCardsMucked    EventType = "cards_mucked"
+	EventTimeBankUsed   EventType = "time_bank_used"
+	EventPotAwarded     EventType = "pot_awarded"
+	EventHandEnded      EventType = "hand_ended"
+	EventChipsAdded     EventType = "chips_added"
+	EventDiscardStarted EventType = "discard_started"
+	EventCardDiscarded  EventType = "card_discarded"
+)
+
+// Event is one entry in a game's log. Seq increases by one with every event
+// the game emits, so a subscriber can tell when it has missed some.
+type Event struct {
+	Seq        int64     `json:"seq"`
+	HandNumber int       `json:"handNumber"`
+	Type       EventType `json:"type"`
+	Time       time.Time `json:"time"`
+	Data       EventData `json:"data"`
+}
+
+type EventData interface {
+	EventType() EventType
+}
+
+// SeatSnapshot is a player as the hand starts, before any forced bets.
+type SeatSnapshot struct {
+	PlayerID         string      `json:"playerId"`
+	Name             string      `json:"name"`
+	Avatar           string      `json:"avatar"`
+	SeatIndex        int         `json:"seatIndex"`
+	Chips            int64       `json:"chips"`
+	State            PlayerState `json:"state"`
+	IsBot            bool        `json:"isBot"`
+	TimeBank         int         `json:"timeBank"`
+	Straddle         bool        `json:"straddle"`
+	AlwaysShow       bool        `json:"alwaysShow"`
+	MissedSmallBlind bool        `json:"missedSmallBlind"`
+	MissedBigBlind   bool        `json:"missedBigBlind"`
+}
+
+// BettingState is the betting round as it stands after an event.
+type BettingState struct {
+	CurrentBet        int64     `json:"currentBet"`
+	MinRaise          int64     `json:"minRaise"`
+	LastRaiseAmount   int64     `json:"lastRaiseAmount"`
+	RaisesThisStreet  int       `json:"raisesThisStreet"`
+	CurrentPlayerSeat int       `json:"currentPlayerSeat"`
+	ActionDeadline    time.Time `json:"actionDeadline"`
+}
+
+// HandStarted opens every hand's log and carries everything needed to
+// rebuild the table from scratch.
+type HandStarted struct {
+	HandID         string         `json:"handId"`
+	Config         GameConfig     `json:"config"`
+	Shuffle        ShuffleRecord  `json:"shuffle"` // the commitment, no server seed yet
+	DealerSeat     int            `json:"dealerSeat"`
+	SmallBlindSeat int            `json:"smallBlindSeat"`
+	BigBlindSeat   int            `json:"bigBlindSeat"`
+	Players        []SeatSnapshot `json:"players"`
+}
+
+type BlindKind string
+
+const (
+	BlindSmall       BlindKind = "small_blind"
+	BlindBig         BlindKind = "big_blind"
+	BlindStraddle    BlindKind = "straddle"
+	BlindAnte        BlindKind = "ante"
+	BlindMissedBig   BlindKind = "missed_big_blind"
+	BlindMissedSmall BlindKind = "missed_small_blind"
+)
+
+// BlindPosted is a forced bet. Dead chips go straight to the pot without
+// counting toward the player's bet or share.
+type BlindPosted struct {
+	PlayerID  string    `json:"playerId"`
+	SeatIndex int       `json:"seatIndex"`
+	Kind      BlindKind `json:"kind"`
+	Amount    int64     `json:"amount"`
+	Dead      bool      `json:"dead"`
+}
+
+type HoleCardsDealt struct {
+	PlayerID  string `json:"playerId"`
+	SeatIndex int    `json:"seatIndex"`
+	Cards     []Card `json:"cards"`
+}
+
+// ActionTaken is a player's action as the table saw it: Action is what it
+// turned out to be (a call for everything left is an all-in) and Amount the
+// chips it put in.
+type ActionTaken struct {
+	PlayerID  string       `json:"playerId"`
+	SeatIndex int          `json:"seatIndex"`
+	Action    ActionType   `json:"action"`
+	Amount    int64        `json:"amount"`
+	Bet       int64        `json:"bet"`
+	Betting   BettingState `json:"betting"`
+}
+
+// StreetDealt starts a betting round or, once nobody can act, deals the
+// next street of a run-out. Preflop carries no cards.
+type StreetDealt struct {
+	Phase   Phase        `json:"phase"`
+	Run     int          `json:"run"`
+	Cards   []Card       `json:"cards"`
+	Board   []Card       `json:"board"`
+	Betting BettingState `json:"betting"`
+}
+
+// BetReturned hands back the part of a bet nobody called once the betting
+// round closes.
+type BetReturned struct {
+	PlayerID  string `json:"playerId"`
+	SeatIndex int    `json:"seatIndex"`
+	Amount    int64  `json:"amount"`
+}
+
+// CardsShown turns some or all of a player's hole cards face up for the
+// whole table.
+type CardsShown struct {
+	PlayerID  string `json:"playerId"`
+	SeatIndex int    `json:"seatIndex"`
+	Cards     []Card `json:"cards"`
+}
+
+type CardsMucked struct {
+	PlayerID  string `json:"playerId"`
+	SeatIndex int    `json:"seatIndex"`
+}
+
+type RunItOffered struct {
+	Offer RunItOffer `json:"offer"`
+}
+
+type TimeBankUsed struct {
+	PlayerID  string    `json:"playerId"`
+	SeatIndex int       `json:"seatIndex"`
+	Seconds   int       `json:"seconds"`
+	TimeBank  int       `json:"timeBank"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// PotAwarded is one pot, or one run's share of it, being paid out.
+type PotAwarded struct {
+	Run int       `json:"run"`
+	Pot PotResult `json:"pot"`
+}
+
+type HandEnded struct {
+	Result       HandResult `json:"result"`
+	NextSeedHash string     `json:"nextSeedHash,omitempty"` // committed before the next hand's client seeds
+}
+
+// ChipsAdded is a top-up to a stack that is not in play. Chips is the
+// stack afterwards.
+type ChipsAdded struct {
+	PlayerID  string `json:"playerId"`
+	SeatIndex int    `json:"seatIndex"`
+	Amount    int64  `json:"amount"`
+	Chips     int64  `json:"chips"`
+}
+
+// DiscardStarted closes a Pineapple betting round and has everyone still in
+// the hand throw away a hole card by Deadline.
+type DiscardStarted struct {
+	Deadline time.Time `json:"deadline"`
+}
+
+// CardDiscarded carries the card thrown away; it is for the log only and
+// never shown to the table.
+type CardDiscarded struct {
+	PlayerID  string `json:"playerId"`
+	SeatIndex int    `json:"seatIndex"`
+	Card      Card   `json:"card"`
+}
+
+func (HandStarted) EventType() EventType    { return EventHandStarted }
+func (BlindPosted) EventType() EventType    { return EventBlindPosted }
+func (HoleCardsDealt) EventType() EventType { return EventHoleCardsDealt }
+func (ActionTaken) EventType() EventType    { return EventActionTaken }
+func (StreetDealt) EventType() EventType    { return EventStreetDealt }
+func (BetReturned) EventType() EventType    { return EventBetReturned }
+func (CardsShown) EventType() EventType     { return EventCardsShown }
+func (CardsMucked) EventType() EventType    { return EventCardsMucked }
+func (RunItOffered) EventType() EventType   { return EventRunItOffered }
+func (TimeBankUsed) EventType() EventType   { return EventTimeBankUsed }
+func (PotAwarded) EventType() EventType     { return EventPotAwarded }
+func (HandEnded) EventType() EventType      { return EventHandEnded }
+func (ChipsAdded) EventType() EventType     { return EventChipsAdded }
+func (DiscardStarted) EventType() EventType { return EventDiscardStarted }
+func (CardDiscarded) EventType() EventType  { return EventCardDiscarded }
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Seq        int64           `json:"seq"`
+		HandNumber int             `json:"handNumber"`
+		Type       EventType       `json:"type"`
+		Time       time.Time       `json:"time"`
+		Data       json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var payload EventData
+	var err error
+	switch raw.Type {
+	case EventHandStarted:
+		var d HandStarted
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventBlindPosted:
+		var d BlindPosted
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventHoleCardsDealt:
+		var d HoleCardsDealt
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventActionTaken:
+		var d ActionTaken
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventStreetDealt:
+		var d StreetDealt
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventBetReturned:
+		var d BetReturned
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventCardsShown:
+		var d CardsShown
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventCardsMucked:
+		var d CardsMucked
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventRunItOffered:
+		var d RunItOffered
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventTimeBankUsed:
+		var d TimeBankUsed
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventPotAwarded:
+		var d PotAwarded
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventHandEnded:
+		var d HandEnded
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventChipsAdded:
+		var d ChipsAdded
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventDiscardStarted:
+		var d DiscardStarted
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	case EventCardDiscarded:
+		var d CardDiscarded
+		err = json.Unmarshal(raw.Data, &d)
+		payload = d
+	default:
+		return fmt.Errorf("unknown event type: %s", raw.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	*e = Event{Seq: raw.Seq, HandNumber: raw.HandNumber, Type: raw.Type, Time: raw.Time, Data: payload}
+	return nil
+}
+
+// emit appends an event to the hand's log and hands it to the subscriber.
+// It runs with the game lock held.
+func (g *Game) emit(data EventData) {
+	g.eventSeq++
+	e := Event{
+		Seq:        g.eventSeq,
+		HandNumber: g.HandNumber,
+		Type:       data.EventType(),
+		Time:       time.Now(),
+		Data:       data,
+	}
+	if e.Type == EventHandStarted {
+		g.events = nil
+	}
+	g.events = append(g.events, e)
+
+	if g.OnEvent != nil {
+		g.OnEvent(e)
+	}
+}
+
+// Events returns the current hand's log from seq onwards.
+func (g *Game) Events(since int64) []Event {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for i, e := range g.events {
+		if e.Seq >= since {
+			return append([]Event(nil), g.events[i:]...)
+		}
+	}
+	return nil
+}
+
+func (g *Game) bettingState() BettingState {
+	return BettingState{
+		CurrentBet:        g.CurrentBet,
+		MinRaise:          g.MinRaise,
+		LastRaiseAmount:   g.LastRaiseAmount,
+		RaisesThisStreet:  g.RaisesThisStreet,
+		CurrentPlayerSeat: g.CurrentPlayerSeat,
+		ActionDeadline:    g.ActionDeadline,
+	}
+}
+
+func (g *Game) setBettingState(b BettingState) {
+	g.CurrentBet = b.CurrentBet
+	g.MinRaise = b.MinRaise
+	g.LastRaiseAmount = b.LastRaiseAmount
+	g.RaisesThisStreet = b.RaisesThisStreet
+	g.CurrentPlayerSeat = b.CurrentPlayerSeat
+	g.ActionDeadline = b.ActionDeadline
+}
+
+func (g *Game) emitHandStarted() {
+	started := HandStarted{
+		HandID:         g.HandID,
+		Config:         g.Config,
+		DealerSeat:     g.DealerSeat,
+		SmallBlindSeat: g.SmallBlindSeat,
+		BigBlindSeat:   g.BigBlindSeat,
+		Players:        make([]SeatSnapshot, 0, len(g.Players)),
+	}
+	if g.Shuffle != nil {
+		started.Shuffle = *g.Shuffle
+	}
+	for _, p := range g.Players {
+		started.Players = append(started.Players, SeatSnapshot{
+			PlayerID:         p.ID,
+			Name:             p.Name,
+			Avatar:           p.Avatar,
+			SeatIndex:        p.SeatIndex,
+			Chips:            p.Chips,
+			State:            p.State,
+			IsBot:            p.IsBot,
+			TimeBank:         p.TimeBank,
+			Straddle:         p.Straddle,
+			AlwaysShow:       p.AlwaysShow,
+			MissedSmallBlind: p.MissedSmallBlind,
+			MissedBigBlind:   p.MissedBigBlind,
+		})
+	}
+	g.emit(started)
+}
+
+func (g *Game) emitStreet(cards []Card) {
+	g.emit(StreetDealt{
+		Phase:   g.Phase,
+		Run:     g.Run,
+		Cards:   cards,
+		Board:   append([]Card(nil), g.CommunityCards...),
+		Betting: g.bettingState(),
+	})
+}
+
+// Replay rebuilds a game from a hand's log, starting at its HandStarted
+// event. The result matches the live game as of the last event.
+func Replay(events []Event) (*Game, error) {
+	if len(events) == 0 || events[0].Type != EventHandStarted {
+		return nil, fmt.Errorf("log must start with hand_started")
+	}
+	started := events[0].Data.(HandStarted)
+	g := NewGame("", started.Config)
+	for _, e := range events {
+		if err := g.Apply(e); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Apply moves a mirror of a game forward by one event. It does not emit.
+func (g *Game) Apply(e Event) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.eventSeq != 0 && e.Seq != g.eventSeq+1 {
+		return fmt.Errorf("event %d out of order after %d", e.Seq, g.eventSeq)
+	}
+
+	switch d := e.Data.(type) {
+	case HandStarted:
+		g.applyHandStarted(e.HandNumber, d)
+	case BlindPosted:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		g.applyBlind(p, d)
+	case HoleCardsDealt:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		p.HoleCards = append(make([]Card, 0, len(d.Cards)), d.Cards...)
+	case ActionTaken:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		p.Chips -= d.Amount
+		p.CurrentBet += d.Amount
+		p.TotalBetInHand += d.Amount
+		p.LastAction = d.Action
+		if d.Action == ActionFold {
+			p.State = StateFolded
+		} else if p.Chips == 0 {
+			p.State = StateAllIn
+		}
+		if d.Betting.CurrentBet > g.CurrentBet {
+			g.LastAggressorSeat = d.SeatIndex
+		}
+		g.setBettingState(d.Betting)
+		if g.Phase == PhaseDiscard {
+			g.closeDiscard()
+		}
+	case StreetDealt:
+		g.applyStreet(d)
+	case BetReturned:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		refundBet(p, d.Amount)
+		g.CurrentBet = p.CurrentBet
+	case CardsShown:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		p.ShownCards = append(p.ShownCards, d.Cards...)
+	case CardsMucked:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		p.Mucked = true
+	case RunItOffered:
+		offer := d.Offer
+		offer.Choices = make(map[string]int)
+		g.RunItOffer = &offer
+		g.CurrentPlayerSeat = -1
+		g.ActionDeadline = offer.Deadline
+	case TimeBankUsed:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		p.TimeBank = d.TimeBank
+		g.ActionDeadline = d.Deadline
+	case PotAwarded:
+		for id, amount := range d.Pot.Winners {
+			if p := g.getPlayerByID(id); p != nil {
+				p.Chips += amount
+			}
+		}
+	case HandEnded:
+		g.applyHandEnded(d)
+	case ChipsAdded:
+		if p := g.getPlayerByID(d.PlayerID); p != nil {
+			p.Chips = d.Chips
+		}
+	case DiscardStarted:
+		g.closeStreet()
+		g.Phase = PhaseDiscard
+		g.CurrentPlayerSeat = -1
+		g.ActionDeadline = d.Deadline
+	case CardDiscarded:
+		p := g.getPlayerByID(d.PlayerID)
+		if p == nil {
+			return fmt.Errorf("player not found: %s", d.PlayerID)
+		}
+		for i, c := range p.HoleCards {
+			if c == d.Card {
+				p.HoleCards = withoutCard(p.HoleCards, i)
+				break
+			}
+		}
+		g.closeDiscard()
+	default:
+		return fmt.Errorf("unknown event type: %s", e.Type)
+	}
+
+	g.eventSeq = e.Seq
+	return nil
+}
+
+func (g *Game) applyHandStarted(handNumber int, d HandStarted) {
+	variant, err := VariantByName(d.Config.Variant)
+	if err != nil {
+		variant = Holdem
+	}
+	shuffle := d.Shuffle
+
+	g.Config = d.Config
+	g.variant = variant
+	g.HandNumber = handNumber
+	g.HandID = d.HandID
+	g.Shuffle = &shuffle
+	g.NextSeedHash = ""
+	g.Phase = PhaseStarting
+	g.DealerSeat = d.DealerSeat
+	g.SmallBlindSeat = d.SmallBlindSeat
+	g.BigBlindSeat = d.BigBlindSeat
+	g.StraddleSeat = -1
+	g.LastAggressorSeat = -1
+	g.CommunityCards = make([]Card, 0, 5)
+	g.Pots = make([]Pot, 0)
+	g.DeadMoney = 0
+	g.RunItOffer = nil
+	g.Boards = nil
+	g.Run = 0
+
+	g.Players = make([]*Player, 0, len(d.Players))
+	for _, s := range d.Players {
+		p := NewPlayer(s.PlayerID, s.Name, s.Chips)
+		p.Avatar = s.Avatar
+		p.SeatIndex = s.SeatIndex
+		p.State = s.State
+		p.IsBot = s.IsBot
+		p.TimeBank = s.TimeBank
+		p.Straddle = s.Straddle
+		p.AlwaysShow = s.AlwaysShow
+		p.MissedSmallBlind = s.MissedSmallBlind
+		p.MissedBigBlind = s.MissedBigBlind
+		p.IsDealer = p.SeatIndex == d.DealerSeat && isDealtIn(p)
+		g.Players = append(g.Players, p)
+	}
+}
+
+func (g *Game) applyBlind(p *Player, d BlindPosted) {
+	p.Chips -= d.Amount
+	if p.Chips == 0 {
+		p.State = StateAllIn
+	}
+	if d.Dead {
+		g.DeadMoney += d.Amount
+		return
+	}
+	p.TotalBetInHand += d.Amount
+	if d.Kind == BlindAnte {
+		return
+	}
+
+	p.CurrentBet += d.Amount
+	switch d.Kind {
+	case BlindSmall:
+		p.IsSmallBlind = true
+		p.LastAction = ActionSmallBlind
+	case BlindBig:
+		p.IsBigBlind = true
+		p.LastAction = ActionBigBlind
+	case BlindMissedBig:
+		p.LastAction = ActionBigBlind
+	case BlindStraddle:
+		p.LastAction = ActionStraddle
+		g.StraddleSeat = p.SeatIndex
+	}
+}
+
+func (g *Game) applyStreet(d StreetDealt) {
+	if d.Phase == PhasePreflop {
+		// Whatever blinds were owed have now been paid.
+		for _, p := range g.getActivePlayers() {
+			p.MissedSmallBlind = false
+			p.MissedBigBlind = false
+		}
+	} else {
+		g.closeStreet()
+	}
+
+	g.RunItOffer = nil
+	g.Phase = d.Phase
+	g.Run = d.Run
+	g.CommunityCards = append(make([]Card, 0, 5), d.Board...)
+	g.setBettingState(d.Betting)
+}
+
+// closeStreet mirrors a betting round closing: bets go to the pot and
+// nobody has acted on the next street yet.
+func (g *Game) closeStreet() {
+	g.collectBets()
+	g.clearStreetBets()
+	for _, p := range g.getActivePlayers() {
+		p.LastAction = ActionNone
+	}
+	g.LastAggressorSeat = -1
+}
+
+func (g *Game) applyHandEnded(d HandEnded) {
+	g.collectBets()
+	g.clearStreetBets()
+	g.RunItOffer = nil
+	if len(d.Result.Runs) > 1 {
+		g.Boards = make([][]Card, 0, len(d.Result.Runs))
+		for _, run := range d.Result.Runs {
+			g.Boards = append(g.Boards, run.Board)
+		}
+		g.CommunityCards = g.Boards[0]
+		g.Run = 0
+	}
+	if d.Result.Shuffle != nil {
+		shuffle := *d.Result.Shuffle
+		g.Shuffle = &shuffle
+	}
+	g.NextSeedHash = d.NextSeedHash
+	g.Phase = PhaseFinished
+}
@@ -0,0 +1,199 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	serverSeedBytes   = 32
+	maxClientSeedSize = 64
+)
+
+type ClientSeed struct {
+	PlayerID string `json:"playerId"`
+	Seed     string `json:"seed"`
+}
+
+// ShuffleRecord is the commit-reveal proof for one hand. SeedHash is
+// published before any card is dealt; ServerSeed stays empty until the hand
+// is over, after which anyone can re-derive the deck order from it.
+type ShuffleRecord struct {
+	HandID      string       `json:"handId"`
+	Variant     string       `json:"variant"`
+	SeedHash    string       `json:"seedHash"`
+	ServerSeed  string       `json:"serverSeed,omitempty"`
+	ClientSeeds []ClientSeed `json:"clientSeeds"`
+}
+
+func NewServerSeed() (string, error) {
+	buf := make([]byte, serverSeedBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func SeedHash(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+// CombineSeeds mixes the server seed with every client seed, in seat order,
+// into the key that drives the shuffle.
+func CombineSeeds(serverSeed string, clientSeeds []ClientSeed) []byte {
+	h := sha256.New()
+	h.Write([]byte(serverSeed))
+	for _, cs := range clientSeeds {
+		h.Write([]byte{0})
+		h.Write([]byte(cs.PlayerID))
+		h.Write([]byte{0})
+		h.Write([]byte(cs.Seed))
+	}
+	return h.Sum(nil)
+}
+
+// DeriveDeck replays a revealed shuffle and returns the deck in dealing order.
+func DeriveDeck(record ShuffleRecord) ([]Card, error) {
+	if record.ServerSeed == "" {
+		return nil, fmt.Errorf("server seed not revealed")
+	}
+	if SeedHash(record.ServerSeed) != record.SeedHash {
+		return nil, fmt.Errorf("server seed does not match commitment")
+	}
+	variant, err := VariantByName(record.Variant)
+	if err != nil {
+		return nil, err
+	}
+
+	deck := variant.NewDeck()
+	deck.ShuffleWithSeed(CombineSeeds(record.ServerSeed, record.ClientSeeds))
+	return deck.Cards(), nil
+}
+
+// seedStream is a deterministic byte stream of HMAC-SHA256(seed, counter)
+// blocks, used so a shuffle can be replayed exactly from its seed.
+type seedStream struct {
+	key     []byte
+	counter uint64
+	buf     []byte
+}
+
+func newSeedStream(seed []byte) *seedStream {
+	return &seedStream{key: seed}
+}
+
+func (s *seedStream) uint64() uint64 {
+	if len(s.buf) < 8 {
+		mac := hmac.New(sha256.New, s.key)
+		var block [8]byte
+		binary.BigEndian.PutUint64(block[:], s.counter)
+		mac.Write(block[:])
+		s.buf = mac.Sum(nil)
+		s.counter++
+	}
+	v := binary.BigEndian.Uint64(s.buf[:8])
+	s.buf = s.buf[8:]
+	return v
+}
+
+// intn returns a uniform value in [0, n) using rejection sampling.
+func (s *seedStream) intn(n int) int {
+	bound := uint64(n)
+	limit := math.MaxUint64 - math.MaxUint64%bound
+	for {
+		if v := s.uint64(); v < limit {
+			return int(v % bound)
+		}
+	}
+}
+
+func (g *Game) SetClientSeed(playerID, seed string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(seed) > maxClientSeedSize {
+		return fmt.Errorf("client seed too long")
+	}
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	player.ClientSeed = seed
+	return nil
+}
+
+// DeckSource puts the deck in dealing order at the start of each hand.
+// seed is the hand's committed shuffle seed; a source that ignores it makes
+// the hand unverifiable and is only meant for tests and scenarios.
+type DeckSource interface {
+	Arrange(deck *Deck, seed []byte) error
+}
+
+// FairShuffle is the default source: a shuffle driven by the hand's seed.
+type FairShuffle struct{}
+
+func (FairShuffle) Arrange(deck *Deck, seed []byte) error {
+	deck.Reset()
+	deck.ShuffleWithSeed(seed)
+	return nil
+}
+
+// StackedDeck deals its cards first, in order, followed by the rest of the
+// deck unshuffled.
+type StackedDeck []Card
+
+func (s StackedDeck) Arrange(deck *Deck, seed []byte) error {
+	return deck.Stack(s)
+}
+
+// commitNextSeed draws the server seed for the next hand and publishes its
+// hash, so the seed is fixed before the client seeds it is mixed with.
+func (g *Game) commitNextSeed() error {
+	seed, err := NewServerSeed()
+	if err != nil {
+		g.nextSeed, g.NextSeedHash = "", ""
+		return err
+	}
+	g.nextSeed = seed
+	g.NextSeedHash = SeedHash(seed)
+	return nil
+}
+
+// shuffleForHand arranges the deck with serverSeed, committed to before the
+// hand, and the seeds of everyone dealt in.
+func (g *Game) shuffleForHand(serverSeed string) error {
+	dealtIn := g.getActivePlayers()
+	sort.Slice(dealtIn, func(i, j int) bool {
+		return dealtIn[i].SeatIndex < dealtIn[j].SeatIndex
+	})
+	clientSeeds := make([]ClientSeed, 0, len(dealtIn))
+	for _, p := range dealtIn {
+		clientSeeds = append(clientSeeds, ClientSeed{PlayerID: p.ID, Seed: p.ClientSeed})
+	}
+
+	g.serverSeed = serverSeed
+	g.Shuffle = &ShuffleRecord{
+		HandID:      g.HandID,
+		Variant:     g.variant.Name(),
+		SeedHash:    SeedHash(serverSeed),
+		ClientSeeds: clientSeeds,
+	}
+
+	return g.deckSource.Arrange(g.Deck, CombineSeeds(serverSeed, clientSeeds))
+}
+
+func (g *Game) revealShuffle() *ShuffleRecord {
+	if g.Shuffle == nil {
+		return nil
+	}
+	g.Shuffle.ServerSeed = g.serverSeed
+	revealed := *g.Shuffle
+	return &revealed
+}
@@ -19,10 +19,11 @@ const (
 	PhaseRiver
 	PhaseShowdown
 	PhaseFinished
+	PhaseDiscard // between streets while everyone throws away a hole card
 )
 
 func (p Phase) String() string {
-	names := []string{"waiting", "starting", "preflop", "flop", "turn", "river", "showdown", "finished"}
+	names := []string{"waiting", "starting", "preflop", "flop", "turn", "river", "showdown", "finished", "discard"}
 	return names[p]
 }
 
@@ -47,13 +48,34 @@ const (
 	ActionAllIn
 	ActionSmallBlind
 	ActionBigBlind
+	ActionStraddle
+	ActionDiscard
 )
 
 func (a ActionType) String() string {
-	names := []string{"none", "fold", "check", "call", "raise", "all_in", "small_blind", "big_blind"}
+	names := []string{"none", "fold", "check", "call", "raise", "all_in", "small_blind", "big_blind", "straddle", "discard"}
 	return names[a]
 }
 
+func ParseActionType(s string) (ActionType, error) {
+	switch s {
+	case "fold":
+		return ActionFold, nil
+	case "check":
+		return ActionCheck, nil
+	case "call":
+		return ActionCall, nil
+	case "raise":
+		return ActionRaise, nil
+	case "all_in", "allin":
+		return ActionAllIn, nil
+	case "discard":
+		return ActionDiscard, nil
+	default:
+		return ActionNone, fmt.Errorf("unknown action: %s", s)
+	}
+}
+
 type Player struct {
 	ID             string      `json:"id"`
 	Name           string      `json:"name"`
@@ -69,6 +91,16 @@ type Player struct {
 	IsSmallBlind   bool        `json:"isSmallBlind"`
 	IsBigBlind     bool        `json:"isBigBlind"`
 	IsBot          bool        `json:"isBot"`
+	TimeBank       int         `json:"timeBank"`
+	ClientSeed     string      `json:"-"`
+
+	ShownCards       []Card `json:"shownCards,omitempty"` // hole cards turned face up for the table
+	Mucked           bool   `json:"mucked"`
+	AlwaysShow       bool   `json:"alwaysShow"` // show losing hands at showdown instead of mucking
+	Straddle         bool   `json:"straddle"`   // opted in to straddle from the straddle seat
+	MissedSmallBlind bool   `json:"missedSmallBlind"`
+	MissedBigBlind   bool   `json:"missedBigBlind"`
+	sitOutNextHand   bool
 }
 
 func NewPlayer(id, name string, chips int64) *Player {
@@ -90,6 +122,17 @@ func (p *Player) Reset() {
 	p.IsDealer = false
 	p.IsSmallBlind = false
 	p.IsBigBlind = false
+	p.ShownCards = nil
+	p.Mucked = false
+}
+
+// VisibleCards returns the hole cards viewerID may see: all of their own,
+// only those turned face up of anyone else's.
+func (p *Player) VisibleCards(viewerID string) []Card {
+	if p.ID == viewerID {
+		return p.HoleCards
+	}
+	return p.ShownCards
 }
 
 func (p *Player) PlaceBet(amount int64) int64 {
@@ -107,69 +150,130 @@ func (p *Player) PlaceBet(amount int64) int64 {
 }
 
 type GameConfig struct {
-	SmallBlind    int64 `json:"smallBlind"`
-	BigBlind      int64 `json:"bigBlind"`
-	Ante          int64 `json:"ante"`
-	MaxPlayers    int   `json:"maxPlayers"`
-	MinPlayers    int   `json:"minPlayers"`
-	ActionTimeout int   `json:"actionTimeout"`
+	SmallBlind          int64 `json:"smallBlind"`
+	BigBlind            int64 `json:"bigBlind"`
+	Ante                int64 `json:"ante"`
+	MaxPlayers          int   `json:"maxPlayers"`
+	MinPlayers          int   `json:"minPlayers"`
+	ActionTimeout       int   `json:"actionTimeout"`
+	TimeBank            int   `json:"timeBank"`            // seconds, also the refill cap
+	TimeBankChunk       int   `json:"timeBankChunk"`       // seconds added per use
+	TimeBankRefill      int   `json:"timeBankRefill"`      // seconds restored per refill
+	TimeBankRefillHands int   `json:"timeBankRefillHands"` // hands between refills
+
+	Variant          string           `json:"variant"`
+	BettingStructure BettingStructure `json:"bettingStructure"`
+	SmallBet         int64            `json:"smallBet"` // fixed limit, defaults to the big blind
+	BigBet           int64            `json:"bigBet"`   // fixed limit, defaults to twice the small bet
+	RaiseCap         int              `json:"raiseCap"` // fixed limit bets and raises per street
+
+	AnteMode AnteMode     `json:"anteMode"` // big blind and button antes default to one big blind
+	Straddle StraddleMode `json:"straddle"`
+
+	MaxRuns      int `json:"maxRuns"`      // boards all-in players may agree to run, 1 disables it
+	RunItTimeout int `json:"runItTimeout"` // seconds to agree before running it once
 }
 
 func DefaultConfig() GameConfig {
 	return GameConfig{
-		SmallBlind:    10,
-		BigBlind:      20,
-		Ante:          0,
-		MaxPlayers:    9,
-		MinPlayers:    2,
-		ActionTimeout: 30,
+		SmallBlind:          10,
+		BigBlind:            20,
+		Ante:                0,
+		MaxPlayers:          9,
+		MinPlayers:          2,
+		ActionTimeout:       30,
+		TimeBank:            60,
+		TimeBankChunk:       15,
+		TimeBankRefill:      15,
+		TimeBankRefillHands: 10,
+		BettingStructure:    NoLimit,
+		RaiseCap:            4,
+		MaxRuns:             1,
+		RunItTimeout:        10,
 	}
 }
 
 type Pot struct {
-	Amount     int64    `json:"amount"`
-	PlayerIDs  []string `json:"playerIds"`
-	IsSidePot  bool     `json:"isSidePot"`
+	Amount    int64    `json:"amount"`
+	PlayerIDs []string `json:"playerIds"`
+	IsSidePot bool     `json:"isSidePot"`
 }
 
 type Game struct {
-	ID                string      `json:"id"`
-	RoomID            string      `json:"roomId"`
-	Config            GameConfig  `json:"config"`
-	Phase             Phase       `json:"phase"`
-	Players           []*Player   `json:"players"`
-	Deck              *Deck       `json:"-"`
-	CommunityCards    []Card      `json:"communityCards"`
-	Pots              []Pot       `json:"pots"`
-	DealerSeat        int         `json:"dealerSeat"`
-	SmallBlindSeat    int         `json:"smallBlindSeat"`
-	BigBlindSeat      int         `json:"bigBlindSeat"`
-	CurrentPlayerSeat int         `json:"currentPlayerSeat"`
-	CurrentBet        int64       `json:"currentBet"`
-	MinRaise          int64       `json:"minRaise"`
-	LastRaiseAmount   int64       `json:"lastRaiseAmount"`
-	HandNumber        int         `json:"handNumber"`
-	ActionDeadline    time.Time   `json:"actionDeadline"`
-	
-	mu sync.RWMutex
-	
-	OnPhaseChange  func(phase Phase)
-	OnPlayerAction func(player *Player, action ActionType, amount int64)
-	OnCardsDealt   func(phase Phase, cards []Card)
-	OnHandComplete func(winners map[string]int64)
+	ID                string         `json:"id"`
+	RoomID            string         `json:"roomId"`
+	Config            GameConfig     `json:"config"`
+	Phase             Phase          `json:"phase"`
+	Players           []*Player      `json:"players"`
+	Deck              *Deck          `json:"-"`
+	CommunityCards    []Card         `json:"communityCards"`
+	Pots              []Pot          `json:"pots"`
+	DealerSeat        int            `json:"dealerSeat"`
+	SmallBlindSeat    int            `json:"smallBlindSeat"`
+	BigBlindSeat      int            `json:"bigBlindSeat"`
+	StraddleSeat      int            `json:"straddleSeat"`
+	LastAggressorSeat int            `json:"lastAggressorSeat"` // last to bet or raise this street
+	CurrentPlayerSeat int            `json:"currentPlayerSeat"`
+	CurrentBet        int64          `json:"currentBet"`
+	MinRaise          int64          `json:"minRaise"`
+	LastRaiseAmount   int64          `json:"lastRaiseAmount"`
+	RaisesThisStreet  int            `json:"raisesThisStreet"`
+	DeadMoney         int64          `json:"deadMoney"`
+	HandNumber        int            `json:"handNumber"`
+	HandID            string         `json:"handId"`
+	Shuffle           *ShuffleRecord `json:"shuffle,omitempty"`
+	NextSeedHash      string         `json:"nextSeedHash,omitempty"` // commitment to the next hand's server seed
+	RunItOffer        *RunItOffer    `json:"runItOffer,omitempty"`
+	Boards            [][]Card       `json:"boards,omitempty"` // one per run when run more than once
+	Run               int            `json:"run"`              // board being dealt
+	ActionDeadline    time.Time      `json:"actionDeadline"`
+
+	variant    Variant
+	deckSource DeckSource
+	serverSeed string
+	nextSeed   string
+	eventSeq   int64
+	events     []Event // the current hand's log
+	mu         sync.RWMutex
+
+	// OnEvent receives every event in order, with the game lock held.
+	OnEvent func(e Event) `json:"-"`
 }
 
 func NewGame(roomID string, config GameConfig) *Game {
-	return &Game{
-		ID:             uuid.New().String(),
-		RoomID:         roomID,
-		Config:         config,
-		Phase:          PhaseWaiting,
-		Players:        make([]*Player, 0, config.MaxPlayers),
-		Deck:           NewDeck(),
-		CommunityCards: make([]Card, 0, 5),
-		Pots:           make([]Pot, 0),
-	}
+	return NewGameWithDeck(roomID, config, FairShuffle{})
+}
+
+// NewGameWithDeck creates a game whose cards come from source instead of
+// the fair shuffle, e.g. a StackedDeck in tests.
+func NewGameWithDeck(roomID string, config GameConfig, source DeckSource) *Game {
+	variant, err := VariantByName(config.Variant)
+	if err != nil {
+		variant = Holdem
+	}
+	config.Variant = variant.Name()
+
+	g := &Game{
+		ID:                uuid.New().String(),
+		RoomID:            roomID,
+		Config:            config,
+		Phase:             PhaseWaiting,
+		Players:           make([]*Player, 0, config.MaxPlayers),
+		Deck:              variant.NewDeck(),
+		CommunityCards:    make([]Card, 0, 5),
+		Pots:              make([]Pot, 0),
+		DealerSeat:        -1,
+		SmallBlindSeat:    -1,
+		BigBlindSeat:      -1,
+		StraddleSeat:      -1,
+		LastAggressorSeat: -1,
+		variant:           variant,
+		deckSource:        source,
+	}
+	// Without a seed committed here the first hand refuses to start and
+	// commits one then.
+	g.commitNextSeed()
+	return g
 }
 
 func (g *Game) AddPlayer(player *Player) error {
@@ -192,6 +296,7 @@ func (g *Game) AddPlayer(player *Player) error {
 	}
 
 	player.SeatIndex = seat
+	player.TimeBank = g.Config.TimeBank
 	g.Players = append(g.Players, player)
 	return nil
 }
@@ -203,7 +308,7 @@ func (g *Game) RemovePlayer(playerID string) error {
 	for i, p := range g.Players {
 		if p.ID == playerID {
 			if g.Phase != PhaseWaiting && g.Phase != PhaseFinished {
-				p.State = StateFolded
+				g.foldLeavingPlayer(p)
 			} else {
 				g.Players = append(g.Players[:i], g.Players[i+1:]...)
 			}
@@ -214,6 +319,96 @@ func (g *Game) RemovePlayer(playerID string) error {
 	return fmt.Errorf("player not found")
 }
 
+// Unseat takes a player away from the table between hands, stack and all,
+// so they can be seated at another one. Returns the stack.
+func (g *Game) Unseat(playerID string) (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.inHand() {
+		return 0, fmt.Errorf("cannot move a player during a hand")
+	}
+	for i, p := range g.Players {
+		if p.ID == playerID {
+			g.Players = append(g.Players[:i], g.Players[i+1:]...)
+			return p.Chips, nil
+		}
+	}
+	return 0, fmt.Errorf("player not found")
+}
+
+// AddChips tops up a stack. Chips a player has in play cannot change until
+// the hand is over, so anyone dealt in has to wait for it to finish.
+func (g *Game) AddChips(playerID string, amount int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if amount <= 0 {
+		return fmt.Errorf("invalid amount")
+	}
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	if g.inHand() && player.State != StateWaiting && player.State != StateSittingOut {
+		return fmt.Errorf("cannot add chips during a hand")
+	}
+
+	player.Chips += amount
+	g.emit(ChipsAdded{PlayerID: player.ID, SeatIndex: player.SeatIndex, Amount: amount, Chips: player.Chips})
+	return nil
+}
+
+// SetStakes changes the blinds and ante. A hand in progress is played out
+// at the stakes it started with, so this waits for it to finish.
+func (g *Game) SetStakes(smallBlind, bigBlind, ante int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if smallBlind <= 0 || bigBlind < smallBlind || ante < 0 {
+		return fmt.Errorf("invalid stakes")
+	}
+	if g.inHand() {
+		return fmt.Errorf("cannot change stakes during a hand")
+	}
+
+	g.Config.SmallBlind = smallBlind
+	g.Config.BigBlind = bigBlind
+	g.Config.Ante = ante
+	return nil
+}
+
+// foldLeavingPlayer folds a player who leaves mid-hand. On their turn it is
+// an ordinary fold; otherwise the hand only ends early if nobody is left to
+// contest it.
+func (g *Game) foldLeavingPlayer(p *Player) {
+	if !isDealtIn(p) {
+		return
+	}
+	if p.SeatIndex == g.CurrentPlayerSeat && g.isBettingRoundLocked() {
+		g.processActionLocked(p.ID, ActionFold, 0)
+		return
+	}
+
+	p.State = StateFolded
+	p.LastAction = ActionFold
+	g.emit(ActionTaken{
+		PlayerID:  p.ID,
+		SeatIndex: p.SeatIndex,
+		Action:    ActionFold,
+		Bet:       p.CurrentBet,
+		Betting:   g.bettingState(),
+	})
+	if len(g.getActivePlayers()) <= 1 {
+		g.RunItOffer = nil
+		g.endHand()
+		return
+	}
+	if g.Phase == PhaseDiscard && g.closeDiscard() {
+		g.dealNextStreet()
+	}
+}
+
 func (g *Game) findEmptySeat() int {
 	occupied := make(map[int]bool)
 	for _, p := range g.Players {
@@ -245,6 +440,12 @@ func (g *Game) CanStartHand() bool {
 	return activeCount >= g.Config.MinPlayers
 }
 
+func (g *Game) IsHandInProgress() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.inHand()
+}
+
 func (g *Game) StartHand() error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -253,31 +454,61 @@ func (g *Game) StartHand() error {
 		return fmt.Errorf("cannot start hand")
 	}
 
+	// The seed was committed before the client seeds were fixed. One drawn
+	// now would not be, so it is kept for the next hand instead.
+	serverSeed := g.nextSeed
+	if serverSeed == "" {
+		if err := g.commitNextSeed(); err != nil {
+			return fmt.Errorf("cannot shuffle: %v", err)
+		}
+		return fmt.Errorf("cannot shuffle: no server seed was committed")
+	}
+
 	g.HandNumber++
+	g.HandID = uuid.New().String()
 	g.Phase = PhaseStarting
+	g.refillTimeBanks()
 
 	for _, p := range g.Players {
+		sittingOut := p.State == StateSittingOut || p.sitOutNextHand
 		p.Reset()
-		if p.Chips > 0 && p.State != StateSittingOut {
+		p.sitOutNextHand = false
+		if sittingOut {
+			p.State = StateSittingOut
+		} else if p.Chips > 0 {
 			p.State = StateActive
 		}
 	}
 
-	g.Pots = []Pot{{Amount: 0, PlayerIDs: make([]string, 0)}}
-	g.Deck.Reset()
-	g.Deck.Shuffle()
+	g.Pots = make([]Pot, 0)
+	g.DeadMoney = 0
+	g.LastAggressorSeat = -1
+	g.RunItOffer = nil
+	g.Boards = nil
+	g.Run = 0
+	if err := g.shuffleForHand(serverSeed); err != nil {
+		g.HandNumber--
+		g.Phase = PhaseWaiting
+		return err
+	}
+	g.nextSeed, g.NextSeedHash = "", ""
 	g.CommunityCards = make([]Card, 0, 5)
 
 	g.moveButton()
+	g.emitHandStarted()
 	g.postBlinds()
 	g.dealHoleCards()
 
 	g.Phase = PhasePreflop
-	g.setNextPlayer(g.BigBlindSeat)
-
-	if g.OnPhaseChange != nil {
-		g.OnPhaseChange(g.Phase)
+	switch {
+	case g.variant.ForcedBets() == ForcedBetsButtonAnte:
+		g.setFirstPlayerAfterDealer()
+	case g.StraddleSeat >= 0:
+		g.setNextPlayer(g.StraddleSeat)
+	default:
+		g.setNextPlayer(g.BigBlindSeat)
 	}
+	g.emitStreet(nil)
 
 	return nil
 }
@@ -298,84 +529,129 @@ func (g *Game) canStartHandLocked() bool {
 }
 
 func (g *Game) moveButton() {
-	activePlayers := g.getActivePlayers()
-	if len(activePlayers) < 2 {
+	if len(g.getActivePlayers()) < 2 {
 		return
 	}
 
-	if g.HandNumber == 1 {
-		g.DealerSeat = activePlayers[0].SeatIndex
-	} else {
-		currentIdx := -1
-		for i, p := range activePlayers {
-			if p.SeatIndex == g.DealerSeat {
-				currentIdx = i
-				break
-			}
-		}
-		nextIdx := (currentIdx + 1) % len(activePlayers)
-		g.DealerSeat = activePlayers[nextIdx].SeatIndex
+	switch {
+	case g.variant.ForcedBets() == ForcedBetsButtonAnte:
+		g.DealerSeat = g.nextPlayerAfter(g.DealerSeat, isDealtIn).SeatIndex
+		g.SmallBlindSeat = -1
+		g.BigBlindSeat = -1
+	case len(g.getActivePlayers()) == 2:
+		// Heads up the button posts the small blind.
+		g.DealerSeat = g.nextPlayerAfter(g.DealerSeat, isDealtIn).SeatIndex
+		g.SmallBlindSeat = g.DealerSeat
+		g.BigBlindSeat = g.nextPlayerAfter(g.DealerSeat, isDealtIn).SeatIndex
+	case g.BigBlindSeat < 0 || g.SmallBlindSeat == g.DealerSeat:
+		// First hand, or back from heads up: start the rotation afresh.
+		g.DealerSeat = g.nextPlayerAfter(g.DealerSeat, isDealtIn).SeatIndex
+		g.SmallBlindSeat = g.nextPlayerAfter(g.DealerSeat, isDealtIn).SeatIndex
+		g.BigBlindSeat = g.nextPlayerAfter(g.SmallBlindSeat, isDealtIn).SeatIndex
+	default:
+		g.moveBlindsDeadButton()
 	}
 
-	for _, p := range g.Players {
-		if p.SeatIndex == g.DealerSeat {
-			p.IsDealer = true
-			break
-		}
-	}
-
-	if len(activePlayers) == 2 {
-		g.SmallBlindSeat = g.DealerSeat
-		for _, p := range activePlayers {
-			if p.SeatIndex != g.DealerSeat {
-				g.BigBlindSeat = p.SeatIndex
-				break
-			}
-		}
-	} else {
-		sbIdx := g.getNextActiveIndex(g.DealerSeat)
-		g.SmallBlindSeat = activePlayers[sbIdx].SeatIndex
-		bbIdx := g.getNextActiveIndex(g.SmallBlindSeat)
-		g.BigBlindSeat = activePlayers[bbIdx].SeatIndex
+	if button := g.getPlayerBySeat(g.DealerSeat); button != nil && isDealtIn(button) {
+		button.IsDealer = true
 	}
 }
 
 func (g *Game) postBlinds() {
-	for _, p := range g.Players {
-		if p.SeatIndex == g.SmallBlindSeat {
-			p.PlaceBet(g.Config.SmallBlind)
-			p.IsSmallBlind = true
-			p.LastAction = ActionSmallBlind
-		} else if p.SeatIndex == g.BigBlindSeat {
-			p.PlaceBet(g.Config.BigBlind)
-			p.IsBigBlind = true
-			p.LastAction = ActionBigBlind
-		}
+	g.StraddleSeat = -1
+	if g.variant.ForcedBets() == ForcedBetsButtonAnte {
+		g.postButtonAnte()
+		return
+	}
+
+	// Per-player antes come out before the blinds; a single big blind or
+	// button ante comes after them.
+	if g.Config.AnteMode == AntePerPlayer {
+		g.postAntes()
+	}
+
+	if p := g.getPlayerBySeat(g.SmallBlindSeat); p != nil && isDealtIn(p) {
+		p.IsSmallBlind = true
+		p.LastAction = ActionSmallBlind
+		g.postBlind(p, BlindSmall, g.Config.SmallBlind)
+	}
+	if p := g.getPlayerBySeat(g.BigBlindSeat); p != nil && isDealtIn(p) {
+		p.IsBigBlind = true
+		p.LastAction = ActionBigBlind
+		g.postBlind(p, BlindBig, g.Config.BigBlind)
 	}
 
 	g.CurrentBet = g.Config.BigBlind
-	g.MinRaise = g.Config.BigBlind
+	g.MinRaise = g.streetMinRaise()
 	g.LastRaiseAmount = g.Config.BigBlind
+	g.RaisesThisStreet = 1
 
-	if g.Config.Ante > 0 {
-		for _, p := range g.getActivePlayers() {
-			p.PlaceBet(g.Config.Ante)
-		}
+	g.collectMissedBlinds()
+
+	if seat := g.straddleSeat(); seat >= 0 {
+		straddle := 2 * g.Config.BigBlind
+		p := g.getPlayerBySeat(seat)
+		p.LastAction = ActionStraddle
+		g.postBlind(p, BlindStraddle, straddle)
+		g.StraddleSeat = seat
+		g.CurrentBet = straddle
+		g.MinRaise = straddle
+		g.LastRaiseAmount = straddle
+		g.RaisesThisStreet++
+	}
+
+	if g.Config.AnteMode != AntePerPlayer {
+		g.postAntes()
 	}
 }
 
+// postButtonAnte has the button put in one ante for the whole table. It is
+// dead money, so nobody starts the hand facing a bet.
+func (g *Game) postButtonAnte() {
+	ante := g.Config.Ante
+	if ante <= 0 {
+		ante = g.Config.BigBlind
+	}
+	if button := g.getPlayerBySeat(g.DealerSeat); button != nil {
+		g.postDeadMoney(button, BlindAnte, ante)
+	}
+
+	g.CurrentBet = 0
+	g.MinRaise = g.streetMinRaise()
+	g.LastRaiseAmount = 0
+	g.RaisesThisStreet = 0
+}
+
+func (g *Game) postDeadMoney(p *Player, kind BlindKind, amount int64) int64 {
+	actual := min(amount, p.Chips)
+	if actual <= 0 {
+		return 0
+	}
+	p.Chips -= actual
+	g.DeadMoney += actual
+	if p.Chips == 0 {
+		p.State = StateAllIn
+	}
+	g.emit(BlindPosted{PlayerID: p.ID, SeatIndex: p.SeatIndex, Kind: kind, Amount: actual, Dead: true})
+	return actual
+}
+
 func (g *Game) dealHoleCards() {
 	activePlayers := g.getActivePlayers()
-	
-	for round := 0; round < 2; round++ {
+
+	for round := 0; round < g.variant.HoleCardCount(); round++ {
 		for _, p := range activePlayers {
 			card, _ := g.Deck.Deal()
 			p.HoleCards = append(p.HoleCards, card)
 		}
 	}
 
-	if g.OnCardsDealt != nil {
-		g.OnCardsDealt(PhasePreflop, nil)
+	for _, p := range activePlayers {
+		g.emit(HoleCardsDealt{
+			PlayerID:  p.ID,
+			SeatIndex: p.SeatIndex,
+			Cards:     append([]Card(nil), p.HoleCards...),
+		})
 	}
 }
 
@@ -383,6 +659,19 @@ func (g *Game) ProcessAction(playerID string, action ActionType, amount int64) e
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	return g.processActionLocked(playerID, action, amount)
+}
+
+func (g *Game) processActionLocked(playerID string, action ActionType, amount int64) error {
+	if action == ActionDiscard {
+		return g.discardLocked(playerID, int(amount))
+	}
+	// The last seat to act is left in CurrentPlayerSeat once a hand is
+	// over, so the phase has to be checked too.
+	if !g.isBettingRoundLocked() {
+		return fmt.Errorf("not time to act")
+	}
+
 	player := g.getPlayerByID(playerID)
 	if player == nil {
 		return fmt.Errorf("player not found")
@@ -396,6 +685,7 @@ func (g *Game) ProcessAction(playerID string, action ActionType, amount int64) e
 		return fmt.Errorf("player cannot act")
 	}
 
+	chips, currentBet := player.Chips, g.CurrentBet
 	var err error
 	switch action {
 	case ActionFold:
@@ -415,12 +705,31 @@ func (g *Game) ProcessAction(playerID string, action ActionType, amount int64) e
 	if err != nil {
 		return err
 	}
-
-	if g.OnPlayerAction != nil {
-		g.OnPlayerAction(player, action, amount)
+	if g.CurrentBet > currentBet {
+		g.LastAggressorSeat = player.SeatIndex
 	}
 
-	g.checkRoundComplete()
+	// The next player is settled before the action is announced so the
+	// event carries the betting round as it now stands.
+	status := g.roundStatus()
+	if status == roundContinues {
+		g.setNextPlayer(g.CurrentPlayerSeat)
+	}
+	g.emit(ActionTaken{
+		PlayerID:  player.ID,
+		SeatIndex: player.SeatIndex,
+		Action:    player.LastAction,
+		Amount:    chips - player.Chips,
+		Bet:       player.CurrentBet,
+		Betting:   g.bettingState(),
+	})
+
+	switch status {
+	case roundHandOver:
+		g.endHand()
+	case roundStreetOver:
+		g.advanceToNextStreet()
+	}
 	return nil
 }
 
@@ -455,7 +764,19 @@ func (g *Game) processCall(p *Player) error {
 }
 
 func (g *Game) processRaise(p *Player, total int64) error {
-	minTotal := g.CurrentBet + g.MinRaise
+	if !g.actionReopened(p) {
+		return fmt.Errorf("action was not reopened, call or fold")
+	}
+	minTotal, maxTotal, ok := g.raiseBounds(p)
+	if !ok {
+		return fmt.Errorf("cannot raise")
+	}
+	if total > maxTotal {
+		if g.Config.BettingStructure != NoLimit {
+			return fmt.Errorf("raise exceeds %s maximum of %d", g.Config.BettingStructure, maxTotal)
+		}
+		total = maxTotal
+	}
 	if total < minTotal && total < p.Chips+p.CurrentBet {
 		return fmt.Errorf("raise too small")
 	}
@@ -463,10 +784,7 @@ func (g *Game) processRaise(p *Player, total int64) error {
 	needed := total - p.CurrentBet
 	p.PlaceBet(needed)
 
-	raiseAmount := p.CurrentBet - g.CurrentBet
-	g.LastRaiseAmount = raiseAmount
-	g.MinRaise = raiseAmount
-	g.CurrentBet = p.CurrentBet
+	g.raiseTo(p.CurrentBet)
 
 	if p.Chips == 0 {
 		p.LastAction = ActionAllIn
@@ -477,23 +795,52 @@ func (g *Game) processRaise(p *Player, total int64) error {
 }
 
 func (g *Game) processAllIn(p *Player) error {
+	if stackTotal := p.Chips + p.CurrentBet; stackTotal > g.CurrentBet {
+		if !g.actionReopened(p) {
+			return fmt.Errorf("action was not reopened, call or fold")
+		}
+		_, maxTotal, ok := g.raiseBounds(p)
+		if !ok || stackTotal > maxTotal {
+			return fmt.Errorf("all-in exceeds %s maximum", g.Config.BettingStructure)
+		}
+	}
+
 	allIn := p.Chips
 	p.PlaceBet(allIn)
 
 	if p.CurrentBet > g.CurrentBet {
-		raiseAmount := p.CurrentBet - g.CurrentBet
-		if raiseAmount >= g.MinRaise {
-			g.MinRaise = raiseAmount
-			g.LastRaiseAmount = raiseAmount
-		}
-		g.CurrentBet = p.CurrentBet
+		g.raiseTo(p.CurrentBet)
 	}
 
 	p.LastAction = ActionAllIn
 	return nil
 }
 
-func (g *Game) checkRoundComplete() {
+// raiseTo moves the bet up to total. Only a full raise resets the minimum
+// raise and counts toward the cap; a short all-in just raises the price of
+// a call.
+func (g *Game) raiseTo(total int64) {
+	raiseAmount := total - g.CurrentBet
+	if g.isFullRaise(raiseAmount) {
+		g.RaisesThisStreet++
+		g.LastRaiseAmount = raiseAmount
+		if raiseAmount > g.MinRaise {
+			g.MinRaise = raiseAmount
+		}
+	}
+	g.CurrentBet = total
+}
+
+type roundOutcome int
+
+const (
+	roundContinues roundOutcome = iota
+	roundStreetOver
+	roundHandOver
+)
+
+// roundStatus reports whether the betting round or the whole hand is over.
+func (g *Game) roundStatus() roundOutcome {
 	activePlayers := g.getActivePlayers()
 	nonFolded := 0
 	for _, p := range activePlayers {
@@ -503,8 +850,7 @@ func (g *Game) checkRoundComplete() {
 	}
 
 	if nonFolded <= 1 {
-		g.endHand()
-		return
+		return roundHandOver
 	}
 
 	canAct := make([]*Player, 0)
@@ -525,199 +871,212 @@ func (g *Game) checkRoundComplete() {
 		}
 	}
 
+	// A short all-in leaves everyone who already acted owing a call, which
+	// allMatched catches, without giving them the right to raise again.
 	allActed := true
 	for _, p := range canAct {
-		if p.LastAction == ActionNone || p.LastAction == ActionSmallBlind || p.LastAction == ActionBigBlind {
+		if !hasActed(p) {
 			allActed = false
 			break
 		}
 	}
 
 	if allMatched && allActed {
-		g.advanceToNextStreet()
-	} else {
-		g.setNextPlayer(g.CurrentPlayerSeat)
+		return roundStreetOver
 	}
+	return roundContinues
 }
 
 func (g *Game) advanceToNextStreet() {
+	if g.Phase == PhaseRiver {
+		g.endHand()
+		return
+	}
+
+	g.returnUncalledBet()
 	g.collectBets()
+	g.clearStreetBets()
 
 	for _, p := range g.getActivePlayers() {
-		p.CurrentBet = 0
 		p.LastAction = ActionNone
 	}
+	g.LastAggressorSeat = -1
 
-	g.CurrentBet = 0
-	g.MinRaise = g.Config.BigBlind
-
-	switch g.Phase {
-	case PhasePreflop:
-		g.dealFlop()
-	case PhaseFlop:
-		g.dealTurn()
-	case PhaseTurn:
-		g.dealRiver()
-	case PhaseRiver:
-		g.endHand()
+	if g.Phase == g.variant.DiscardAfter() {
+		g.startDiscard()
 		return
 	}
+	g.dealNextStreet()
+}
 
-	g.Phase++
-
+// dealNextStreet opens the next betting round, or runs out the board when
+// nobody is left to bet. A discard still to come is waited for street by
+// street, so nobody turns up a card they would have thrown away.
+func (g *Game) dealNextStreet() {
 	canAct := 0
 	for _, p := range g.getActivePlayers() {
 		if p.State == StateActive {
 			canAct++
 		}
 	}
-
-	if canAct <= 1 {
-		g.runOutBoard()
-	} else {
-		g.setFirstPlayerAfterDealer()
-		if g.OnPhaseChange != nil {
-			g.OnPhaseChange(g.Phase)
-		}
+	if canAct <= 1 && !g.discardPending() {
+		g.startRunOut()
+		return
 	}
-}
 
-func (g *Game) dealFlop() {
-	g.Deck.Burn()
-	cards, _ := g.Deck.DealN(3)
-	g.CommunityCards = append(g.CommunityCards, cards...)
-	if g.OnCardsDealt != nil {
-		g.OnCardsDealt(PhaseFlop, cards)
+	cards := g.dealStreet()
+	g.MinRaise = g.streetMinRaise()
+	if canAct <= 1 {
+		g.CurrentPlayerSeat = -1
+		g.emitStreet(cards)
+		g.advanceToNextStreet()
+		return
 	}
+	g.setFirstPlayerAfterDealer()
+	g.emitStreet(cards)
 }
 
-func (g *Game) dealTurn() {
+// dealStreet burns and deals the next street of the board and moves the
+// phase along with it.
+func (g *Game) dealStreet() []Card {
 	g.Deck.Burn()
-	card, _ := g.Deck.Deal()
-	g.CommunityCards = append(g.CommunityCards, card)
-	if g.OnCardsDealt != nil {
-		g.OnCardsDealt(PhaseTurn, []Card{card})
+	var cards []Card
+	switch len(g.CommunityCards) {
+	case 0:
+		cards, _ = g.Deck.DealN(3)
+		g.Phase = PhaseFlop
+	case 3:
+		cards, _ = g.Deck.DealN(1)
+		g.Phase = PhaseTurn
+	default:
+		cards, _ = g.Deck.DealN(1)
+		g.Phase = PhaseRiver
 	}
+	g.CommunityCards = append(g.CommunityCards, cards...)
+	return cards
 }
 
-func (g *Game) dealRiver() {
-	g.Deck.Burn()
-	card, _ := g.Deck.Deal()
-	g.CommunityCards = append(g.CommunityCards, card)
-	if g.OnCardsDealt != nil {
-		g.OnCardsDealt(PhaseRiver, []Card{card})
-	}
+// runOutBoard deals the rest of the board once and shows down.
+func (g *Game) runOutBoard() {
+	g.dealRemainingStreets()
+	g.endHand()
 }
 
-func (g *Game) runOutBoard() {
+// dealRemainingStreets deals the rest of the board street by street, with
+// nobody left to act.
+func (g *Game) dealRemainingStreets() {
 	for len(g.CommunityCards) < 5 {
-		g.Deck.Burn()
-		card, _ := g.Deck.Deal()
-		g.CommunityCards = append(g.CommunityCards, card)
+		g.emitStreet(g.dealStreet())
 	}
-	g.endHand()
 }
 
 func (g *Game) collectBets() {
-	// Simplified pot collection
-	var totalBets int64
-	eligibleIDs := make([]string, 0)
-
-	for _, p := range g.Players {
-		if p.TotalBetInHand > 0 {
-			totalBets += p.CurrentBet
-		}
-		if p.State == StateActive || p.State == StateAllIn {
-			eligibleIDs = append(eligibleIDs, p.ID)
-		}
-	}
-
-	if len(g.Pots) == 0 {
-		g.Pots = []Pot{{Amount: 0, PlayerIDs: make([]string, 0)}}
-	}
-
-	g.Pots[0].Amount += totalBets
-	g.Pots[0].PlayerIDs = eligibleIDs
+	g.Pots = g.calculatePots()
 }
 
 func (g *Game) endHand() {
+	g.returnUncalledBet()
 	g.Phase = PhaseShowdown
 	g.collectBets()
+	g.clearStreetBets()
 
-	activePlayers := make([]*Player, 0)
-	for _, p := range g.Players {
-		if p.State == StateActive || p.State == StateAllIn {
-			activePlayers = append(activePlayers, p)
-		}
+	boards := g.Boards
+	if len(boards) == 0 {
+		boards = [][]Card{g.CommunityCards}
 	}
 
-	winners := make(map[string]int64)
+	result := &HandResult{
+		HandNumber: g.HandNumber,
+		HandID:     g.HandID,
+		Winners:    make(map[string]int64),
+		Pots:       make([]PotResult, 0, len(g.Pots)),
+	}
 
-	if len(activePlayers) == 1 {
-		winners[activePlayers[0].ID] = g.getTotalPot()
-	} else {
-		winners = g.determineWinners(activePlayers)
+	runs := make([]RunResult, len(boards))
+	hands := make([]map[string]HandRank, len(boards))
+	for r, board := range boards {
+		runs[r] = RunResult{Index: r, Board: board, Pots: make([]PotResult, 0, len(g.Pots))}
+		hands[r] = g.evaluateHands(board)
 	}
+	if len(g.getActivePlayers()) > 1 {
+		result.Showdown = g.showdown(hands[0])
+		for r := range hands {
+			for _, p := range g.Players {
+				if p.Mucked {
+					delete(hands[r], p.ID)
+				}
+			}
+			if len(boards) > 1 {
+				runs[r].Hands = hands[r]
+				runs[r].Descriptions = make(map[string]map[Locale]string, len(hands[r]))
+				for id, hand := range hands[r] {
+					runs[r].Descriptions[id] = hand.Descriptions()
+				}
+			}
+		}
+	}
+
+	// Each run is awarded its share of every pot on its own.
+	for i, pot := range g.Pots {
+		total := PotResult{
+			Index:     i,
+			Amount:    pot.Amount,
+			IsSidePot: pot.IsSidePot,
+			PlayerIDs: pot.PlayerIDs,
+			Winners:   make(map[string]int64),
+		}
+		for r, share := range splitAmount(pot.Amount, len(boards)) {
+			runPot := pot
+			runPot.Amount = share
+			potResult := g.awardPot(i, runPot, hands[r])
+			for id, amount := range potResult.Winners {
+				total.Winners[id] += amount
+			}
+			runs[r].Pots = append(runs[r].Pots, potResult)
+			g.emit(PotAwarded{Run: r, Pot: potResult})
+		}
 
-	for id, amount := range winners {
-		for _, p := range g.Players {
-			if p.ID == id {
+		for id, amount := range total.Winners {
+			if p := g.getPlayerByID(id); p != nil {
 				p.Chips += amount
-				break
 			}
+			result.Winners[id] += amount
 		}
+		result.Pots = append(result.Pots, total)
+	}
+	if len(boards) > 1 {
+		result.Runs = runs
 	}
 
 	g.Phase = PhaseFinished
+	result.Shuffle = g.revealShuffle()
+	g.commitNextSeed()
+	g.emit(HandEnded{Result: *result, NextSeedHash: g.NextSeedHash})
+}
 
-	if g.OnHandComplete != nil {
-		g.OnHandComplete(winners)
+// clearStreetBets takes the last street's bets off the table once they are
+// in the pot.
+func (g *Game) clearStreetBets() {
+	for _, p := range g.Players {
+		p.CurrentBet = 0
 	}
+	g.CurrentBet = 0
+	g.RaisesThisStreet = 0
 }
 
-func (g *Game) determineWinners(players []*Player) map[string]int64 {
-	winners := make(map[string]int64)
-	
-	if len(g.CommunityCards) < 5 {
-		// Not enough cards, split pot
-		pot := g.getTotalPot()
-		share := pot / int64(len(players))
-		for _, p := range players {
-			winners[p.ID] = share
-		}
-		return winners
+func (g *Game) evaluateHands(board []Card) map[string]HandRank {
+	hands := make(map[string]HandRank)
+	contenders := g.getActivePlayers()
+	if len(contenders) <= 1 {
+		return hands
 	}
-
-	var bestHand HandRank
-	var bestPlayers []*Player
-
-	for _, p := range players {
-		if len(p.HoleCards) < 2 {
+	for _, p := range contenders {
+		if len(p.HoleCards) == 0 {
 			continue
 		}
-		hand := EvaluateHand(p.HoleCards, g.CommunityCards)
-		cmp := hand.Compare(bestHand)
-		if cmp > 0 {
-			bestHand = hand
-			bestPlayers = []*Player{p}
-		} else if cmp == 0 {
-			bestPlayers = append(bestPlayers, p)
-		}
+		hands[p.ID] = g.variant.EvaluateHand(p.HoleCards, board)
 	}
-
-	pot := g.getTotalPot()
-	share := pot / int64(len(bestPlayers))
-	remainder := pot % int64(len(bestPlayers))
-
-	for i, p := range bestPlayers {
-		amt := share
-		if i == 0 {
-			amt += remainder
-		}
-		winners[p.ID] = amt
-	}
-
-	return winners
+	return hands
 }
 
 func (g *Game) getTotalPot() int64 {
@@ -747,84 +1106,24 @@ func (g *Game) getPlayerByID(id string) *Player {
 	return nil
 }
 
-func (g *Game) getNextActiveIndex(currentSeat int) int {
-	activePlayers := g.getActivePlayers()
-	if len(activePlayers) == 0 {
-		return -1
-	}
-
-	currentIdx := -1
-	for i, p := range activePlayers {
-		if p.SeatIndex == currentSeat {
-			currentIdx = i
-			break
-		}
-	}
-
-	if currentIdx < 0 {
-		return 0
-	}
-
-	return (currentIdx + 1) % len(activePlayers)
-}
-
 func (g *Game) setNextPlayer(currentSeat int) {
-	canAct := make([]*Player, 0)
-	for _, p := range g.getActivePlayers() {
-		if p.State == StateActive {
-			canAct = append(canAct, p)
-		}
-	}
-
-	if len(canAct) == 0 {
+	next := g.nextPlayerAfter(currentSeat, func(p *Player) bool {
+		return p.State == StateActive
+	})
+	if next == nil {
 		return
 	}
 
-	currentIdx := -1
-	for i, p := range canAct {
-		if p.SeatIndex == currentSeat {
-			currentIdx = i
-			break
-		}
-	}
-
-	if currentIdx < 0 {
-		currentIdx = 0
-	} else {
-		currentIdx = (currentIdx + 1) % len(canAct)
-	}
-
-	g.CurrentPlayerSeat = canAct[currentIdx].SeatIndex
+	g.CurrentPlayerSeat = next.SeatIndex
 	g.ActionDeadline = time.Now().Add(time.Duration(g.Config.ActionTimeout) * time.Second)
 }
 
 func (g *Game) setFirstPlayerAfterDealer() {
-	canAct := make([]*Player, 0)
-	for _, p := range g.getActivePlayers() {
-		if p.State == StateActive {
-			canAct = append(canAct, p)
-		}
-	}
-
-	if len(canAct) == 0 {
-		return
-	}
-
-	dealerIdx := -1
-	for i, p := range canAct {
-		if p.SeatIndex == g.DealerSeat {
-			dealerIdx = i
-			break
-		}
-	}
-
-	if dealerIdx < 0 {
-		dealerIdx = 0
-	}
+	g.setNextPlayer(g.DealerSeat)
+}
 
-	firstIdx := (dealerIdx + 1) % len(canAct)
-	g.CurrentPlayerSeat = canAct[firstIdx].SeatIndex
-	g.ActionDeadline = time.Now().Add(time.Duration(g.Config.ActionTimeout) * time.Second)
+func (g *Game) Variant() Variant {
+	return g.variant
 }
 
 func (g *Game) GetCurrentPlayer() *Player {
@@ -864,13 +1163,10 @@ func (g *Game) GetRaiseLimits(playerID string) (minRaise, maxRaise int64) {
 		return 0, 0
 	}
 
-	minRaise = g.CurrentBet + g.MinRaise
-	maxRaise = player.Chips + player.CurrentBet
-
-	if minRaise > maxRaise {
-		minRaise = maxRaise
+	minRaise, maxRaise, ok := g.raiseBounds(player)
+	if !ok {
+		return 0, 0
 	}
-
 	return minRaise, maxRaise
 }
 
@@ -1,7 +1,12 @@
 package game
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewDeck(t *testing.T) {
@@ -431,3 +436,1591 @@ func TestGameProcessAction(t *testing.T) {
 		t.Errorf("Failed to process action: %v", err)
 	}
 }
+
+func TestCalculatePotsSidePots(t *testing.T) {
+	game := NewGame("test-room", DefaultConfig())
+
+	short := &Player{ID: "short", SeatIndex: 0, TotalBetInHand: 100, State: StateAllIn}
+	mid := &Player{ID: "mid", SeatIndex: 1, TotalBetInHand: 300, State: StateAllIn}
+	deep := &Player{ID: "deep", SeatIndex: 2, TotalBetInHand: 500, Chips: 500, State: StateActive}
+	folded := &Player{ID: "folded", SeatIndex: 3, TotalBetInHand: 50, State: StateFolded}
+	game.Players = []*Player{short, mid, deep, folded}
+
+	pots := game.calculatePots()
+	if len(pots) != 3 {
+		t.Fatalf("Expected 3 pots, got %d", len(pots))
+	}
+
+	expected := []struct {
+		amount  int64
+		players int
+	}{
+		{350, 3},
+		{400, 2},
+		{200, 1},
+	}
+	for i, exp := range expected {
+		if pots[i].Amount != exp.amount {
+			t.Errorf("Pot %d: expected amount %d, got %d", i, exp.amount, pots[i].Amount)
+		}
+		if len(pots[i].PlayerIDs) != exp.players {
+			t.Errorf("Pot %d: expected %d eligible players, got %d", i, exp.players, len(pots[i].PlayerIDs))
+		}
+		if pots[i].IsSidePot != (i > 0) {
+			t.Errorf("Pot %d: unexpected IsSidePot %v", i, pots[i].IsSidePot)
+		}
+	}
+}
+
+func TestEndHandShortAllInWinsMainPotOnly(t *testing.T) {
+	game := NewGame("test-room", DefaultConfig())
+
+	short := &Player{ID: "short", SeatIndex: 0, TotalBetInHand: 100, State: StateAllIn,
+		HoleCards: []Card{{Suit: Hearts, Rank: Ace}, {Suit: Spades, Rank: Ace}}}
+	deep1 := &Player{ID: "deep1", SeatIndex: 1, TotalBetInHand: 400, Chips: 600, State: StateActive,
+		HoleCards: []Card{{Suit: Hearts, Rank: King}, {Suit: Spades, Rank: King}}}
+	deep2 := &Player{ID: "deep2", SeatIndex: 2, TotalBetInHand: 400, Chips: 600, State: StateActive,
+		HoleCards: []Card{{Suit: Hearts, Rank: Seven}, {Suit: Spades, Rank: Two}}}
+	game.Players = []*Player{short, deep1, deep2}
+	game.CommunityCards = []Card{
+		{Suit: Diamonds, Rank: Nine},
+		{Suit: Clubs, Rank: Eight},
+		{Suit: Diamonds, Rank: Four},
+		{Suit: Clubs, Rank: Three},
+		{Suit: Diamonds, Rank: Jack},
+	}
+
+	var result *HandResult
+	game.OnEvent = func(e Event) {
+		if ended, ok := e.Data.(HandEnded); ok {
+			result = &ended.Result
+		}
+	}
+	game.endHand()
+
+	if short.Chips != 300 {
+		t.Errorf("Short stack should win the 300 main pot, got %d", short.Chips)
+	}
+	if deep1.Chips != 1200 {
+		t.Errorf("Kings should win the 600 side pot, got %d chips", deep1.Chips)
+	}
+	if result == nil || len(result.Pots) != 2 {
+		t.Fatal("Hand result should report both pots")
+	}
+	if result.Pots[1].Winners["deep1"] != 600 {
+		t.Errorf("Side pot should go to deep1, got %v", result.Pots[1].Winners)
+	}
+}
+
+func TestEndHandOddChipGoesLeftOfButton(t *testing.T) {
+	game := NewGame("test-room", DefaultConfig())
+	game.DealerSeat = 0
+
+	dealer := &Player{ID: "dealer", SeatIndex: 0, TotalBetInHand: 15, State: StateActive,
+		HoleCards: []Card{{Suit: Hearts, Rank: Two}, {Suit: Spades, Rank: Three}}}
+	sb := &Player{ID: "sb", SeatIndex: 1, TotalBetInHand: 15, State: StateActive,
+		HoleCards: []Card{{Suit: Clubs, Rank: Two}, {Suit: Diamonds, Rank: Three}}}
+	folded := &Player{ID: "bb", SeatIndex: 2, TotalBetInHand: 1, State: StateFolded}
+	game.Players = []*Player{dealer, sb, folded}
+	game.CommunityCards = []Card{
+		{Suit: Hearts, Rank: Ace},
+		{Suit: Spades, Rank: King},
+		{Suit: Diamonds, Rank: Queen},
+		{Suit: Clubs, Rank: Jack},
+		{Suit: Hearts, Rank: Nine},
+	}
+
+	game.endHand()
+
+	if sb.Chips != 16 || dealer.Chips != 15 {
+		t.Errorf("Odd chip should go to the small blind, got sb=%d dealer=%d", sb.Chips, dealer.Chips)
+	}
+}
+
+func TestExpireActionUsesTimeBankThenFolds(t *testing.T) {
+	config := DefaultConfig()
+	config.TimeBank = 10
+	config.TimeBankChunk = 10
+	game := NewGame("test-room", config)
+
+	game.AddPlayer(NewPlayer("p1", "Player 1", 1000))
+	game.AddPlayer(NewPlayer("p2", "Player 2", 1000))
+	game.StartHand()
+
+	current := game.GetCurrentPlayer()
+	now := game.ActionDeadline.Add(time.Second)
+
+	result := game.ExpireAction(now)
+	if result == nil || result.TimeBankUsed != 10 {
+		t.Fatalf("Expected time bank to be used, got %+v", result)
+	}
+	if current.TimeBank != 0 {
+		t.Errorf("Time bank should be empty, got %d", current.TimeBank)
+	}
+	if game.ExpireAction(now) != nil {
+		t.Error("Extended deadline should not expire immediately")
+	}
+
+	result = game.ExpireAction(game.ActionDeadline.Add(time.Second))
+	if result == nil || result.Action != ActionFold {
+		t.Fatalf("Expected auto-fold facing the big blind, got %+v", result)
+	}
+	if current.State != StateFolded {
+		t.Error("Timed out player should be folded")
+	}
+}
+
+func TestExpireActionChecksWhenFree(t *testing.T) {
+	config := DefaultConfig()
+	config.TimeBank = 0
+	game := NewGame("test-room", config)
+
+	game.AddPlayer(NewPlayer("p1", "Player 1", 1000))
+	game.AddPlayer(NewPlayer("p2", "Player 2", 1000))
+	game.StartHand()
+
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionCall, 0)
+
+	bb := game.GetCurrentPlayer()
+	result := game.ExpireAction(game.ActionDeadline.Add(time.Second))
+	if result == nil || result.Action != ActionCheck {
+		t.Fatalf("Expected auto-check for the big blind, got %+v", result)
+	}
+	if bb.State != StateActive || game.Phase != PhaseFlop {
+		t.Errorf("Auto-check should close preflop, got phase %v", game.Phase)
+	}
+}
+
+func newHeadsUpGame(config GameConfig) *Game {
+	game := NewGame("test-room", config)
+	game.AddPlayer(NewPlayer("p1", "Player 1", 1000))
+	game.AddPlayer(NewPlayer("p2", "Player 2", 1000))
+	game.StartHand()
+	return game
+}
+
+func TestPotLimitRaiseLimits(t *testing.T) {
+	config := DefaultConfig()
+	config.BettingStructure = PotLimit
+	game := newHeadsUpGame(config)
+
+	sb := game.GetCurrentPlayer()
+	minRaise, maxRaise := game.GetRaiseLimits(sb.ID)
+	if minRaise != 40 || maxRaise != 60 {
+		t.Errorf("Expected pot-limit bounds 40-60, got %d-%d", minRaise, maxRaise)
+	}
+
+	if err := game.ProcessAction(sb.ID, ActionRaise, 80); err == nil {
+		t.Error("Raise above the pot should be rejected")
+	}
+	if err := game.ProcessAction(sb.ID, ActionAllIn, 0); err == nil {
+		t.Error("All-in above the pot should be rejected")
+	}
+	if err := game.ProcessAction(sb.ID, ActionRaise, 60); err != nil {
+		t.Fatalf("Pot-sized raise should be accepted: %v", err)
+	}
+
+	// Facing 60 with 20 in: pot is 80 on the table plus the 40 call.
+	bb := game.GetCurrentPlayer()
+	minRaise, maxRaise = game.GetRaiseLimits(bb.ID)
+	if minRaise != 100 || maxRaise != 180 {
+		t.Errorf("Expected pot-limit bounds 100-180, got %d-%d", minRaise, maxRaise)
+	}
+}
+
+func TestFixedLimitBetSizesAndCap(t *testing.T) {
+	config := DefaultConfig()
+	config.BettingStructure = FixedLimit
+	config.RaiseCap = 4
+	game := newHeadsUpGame(config)
+
+	for _, expected := range []int64{40, 60, 80} {
+		current := game.GetCurrentPlayer()
+		minRaise, maxRaise := game.GetRaiseLimits(current.ID)
+		if minRaise != expected || maxRaise != expected {
+			t.Fatalf("Expected fixed raise to %d, got %d-%d", expected, minRaise, maxRaise)
+		}
+		if err := game.ProcessAction(current.ID, ActionRaise, expected); err != nil {
+			t.Fatalf("Raise to %d failed: %v", expected, err)
+		}
+	}
+
+	current := game.GetCurrentPlayer()
+	if minRaise, maxRaise := game.GetRaiseLimits(current.ID); minRaise != 0 || maxRaise != 0 {
+		t.Errorf("Street should be capped, got %d-%d", minRaise, maxRaise)
+	}
+	if err := game.ProcessAction(current.ID, ActionRaise, 100); err == nil {
+		t.Error("Raise past the cap should be rejected")
+	}
+	game.ProcessAction(current.ID, ActionCall, 0)
+
+	// Turn bets use the big bet.
+	for game.Phase < PhaseTurn {
+		game.ProcessAction(game.GetCurrentPlayer().ID, ActionCheck, 0)
+	}
+	current = game.GetCurrentPlayer()
+	if minRaise, _ := game.GetRaiseLimits(current.ID); minRaise != 40 {
+		t.Errorf("Expected turn bet of 40, got %d", minRaise)
+	}
+}
+
+func TestEvaluateOmahaHandRequiresTwoHoleCards(t *testing.T) {
+	holeCards := []Card{
+		{Suit: Hearts, Rank: Ace},
+		{Suit: Spades, Rank: Three},
+		{Suit: Clubs, Rank: Four},
+		{Suit: Diamonds, Rank: Nine},
+	}
+	communityCards := []Card{
+		{Suit: Hearts, Rank: Two},
+		{Suit: Hearts, Rank: Five},
+		{Suit: Hearts, Rank: Eight},
+		{Suit: Hearts, Rank: Jack},
+		{Suit: Clubs, Rank: King},
+	}
+
+	if result := EvaluateHand(holeCards, communityCards); result.Type != Flush {
+		t.Errorf("Hold'em evaluation should see the flush, got %v", result.Type)
+	}
+
+	// A single heart in hand cannot make a flush, and the wheel would need
+	// three hole cards.
+	result := EvaluateOmahaHand(holeCards, communityCards)
+	expected := []Rank{Ace, King, Jack, Nine, Eight}
+	if result.Type != HighCard || len(result.Kickers) != len(expected) {
+		t.Fatalf("Expected Ace-high in Omaha, got %v %v", result.Type, result.Kickers)
+	}
+	for i, rank := range expected {
+		if result.Kickers[i] != rank {
+			t.Errorf("Expected kickers %v, got %v", expected, result.Kickers)
+			break
+		}
+	}
+}
+
+func TestOmahaDealsFourHoleCards(t *testing.T) {
+	config := DefaultConfig()
+	config.Variant = "omaha"
+	game := newHeadsUpGame(config)
+
+	if game.Variant() != Omaha {
+		t.Fatalf("Expected Omaha variant, got %s", game.Variant().Name())
+	}
+	for _, p := range game.Players {
+		if len(p.HoleCards) != 4 {
+			t.Errorf("Player should have 4 hole cards, got %d", len(p.HoleCards))
+		}
+	}
+}
+
+func TestShortDeckHasThirtySixCards(t *testing.T) {
+	deck := NewShortDeck()
+	if deck.RemainingCards() != 36 {
+		t.Fatalf("Expected 36 cards, got %d", deck.RemainingCards())
+	}
+	for deck.RemainingCards() > 0 {
+		card, _ := deck.Deal()
+		if card.Rank < Six {
+			t.Errorf("Short deck should not contain %s", card)
+		}
+	}
+}
+
+func TestShortDeckWheelAndFlushOverFullHouse(t *testing.T) {
+	wheel := EvaluateShortDeckHand(
+		[]Card{{Suit: Hearts, Rank: Ace}, {Suit: Spades, Rank: Six}},
+		[]Card{{Suit: Diamonds, Rank: Seven}, {Suit: Clubs, Rank: Eight}, {Suit: Hearts, Rank: Nine}, {Suit: Spades, Rank: King}, {Suit: Diamonds, Rank: King}},
+	)
+	if wheel.Type != Straight || wheel.Kickers[0] != Nine {
+		t.Errorf("Expected Nine-high straight, got %v %v", wheel.Type, wheel.Kickers)
+	}
+
+	board := []Card{{Suit: Hearts, Rank: King}, {Suit: Hearts, Rank: Ten}, {Suit: Hearts, Rank: Seven}, {Suit: Clubs, Rank: Ten}, {Suit: Spades, Rank: Six}}
+	flush := EvaluateShortDeckHand([]Card{{Suit: Hearts, Rank: Ace}, {Suit: Hearts, Rank: Eight}}, board)
+	fullHouse := EvaluateShortDeckHand([]Card{{Suit: Diamonds, Rank: King}, {Suit: Spades, Rank: King}}, board)
+
+	if flush.Type != Flush || fullHouse.Type != FullHouse {
+		t.Fatalf("Expected flush and full house, got %v and %v", flush.Type, fullHouse.Type)
+	}
+	if flush.Compare(fullHouse) <= 0 {
+		t.Error("Flush should beat full house in short deck")
+	}
+	if EvaluateHand(flush.Cards, nil).Compare(EvaluateHand(fullHouse.Cards, nil)) >= 0 {
+		t.Error("Full house should still beat flush in hold'em")
+	}
+}
+
+func TestShortDeckButtonAnte(t *testing.T) {
+	config := DefaultConfig()
+	config.Variant = "short_deck"
+	config.Ante = 30
+	game := NewGame("test-room", config)
+	for _, id := range []string{"p1", "p2", "p3"} {
+		game.AddPlayer(NewPlayer(id, id, 1000))
+	}
+	game.StartHand()
+
+	button := game.getPlayerBySeat(game.DealerSeat)
+	if button.Chips != 970 || game.DeadMoney != 30 {
+		t.Errorf("Button should post a 30 dead ante, got chips=%d dead=%d", button.Chips, game.DeadMoney)
+	}
+	if game.CurrentBet != 0 {
+		t.Errorf("Nobody should face a bet preflop, got %d", game.CurrentBet)
+	}
+	if game.CurrentPlayerSeat != (game.DealerSeat+1)%3 {
+		t.Errorf("Action should start left of the button, got seat %d", game.CurrentPlayerSeat)
+	}
+
+	for game.Phase == PhasePreflop {
+		game.ProcessAction(game.GetCurrentPlayer().ID, ActionCheck, 0)
+	}
+	if len(game.Pots) != 1 || game.Pots[0].Amount != 30 || len(game.Pots[0].PlayerIDs) != 3 {
+		t.Errorf("Dead ante should form a pot for all three players, got %+v", game.Pots)
+	}
+}
+
+func TestFoldedChipsAreKeptWhenOnlyADeadAnteIsLive(t *testing.T) {
+	config := DefaultConfig()
+	config.Variant = "short_deck"
+	config.Ante = 5
+	game := NewGame("test-room", config)
+	game.AddPlayer(NewPlayer("p1", "p1", 5))
+	game.AddPlayer(NewPlayer("p2", "p2", 1000))
+	game.AddPlayer(NewPlayer("p3", "p3", 1000))
+	game.StartHand()
+
+	button := game.getPlayerBySeat(game.DealerSeat)
+	if button.ID != "p1" || button.State != StateAllIn {
+		t.Fatalf("Expected p1 all in for the button ante, got %s in state %v", button.ID, button.State)
+	}
+	if err := game.ProcessAction("p2", ActionRaise, 100); err != nil {
+		t.Fatalf("Raise failed: %v", err)
+	}
+	game.ProcessAction("p3", ActionCall, 0)
+	game.ProcessAction("p2", ActionFold, 0)
+	game.ProcessAction("p3", ActionFold, 0)
+
+	if game.Phase != PhaseFinished {
+		t.Fatalf("Expected the hand over, got %s", game.Phase)
+	}
+	var chips int64
+	for _, p := range game.Players {
+		chips += p.Chips
+	}
+	if chips != 2005 {
+		t.Errorf("Expected all 2005 chips still at the table, got %d", chips)
+	}
+	if err := game.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShuffleWithSeedIsDeterministic(t *testing.T) {
+	a, b := NewDeck(), NewDeck()
+	a.ShuffleWithSeed([]byte("seed"))
+	b.ShuffleWithSeed([]byte("seed"))
+	for i, c := range a.Cards() {
+		if b.Cards()[i] != c {
+			t.Fatalf("Same seed should give the same order, differs at %d", i)
+		}
+	}
+
+	b.ShuffleWithSeed([]byte("other seed"))
+	same := true
+	for i, c := range a.Cards() {
+		if b.Cards()[i] != c {
+			same = false
+		}
+	}
+	if same {
+		t.Error("Different seeds should give different orders")
+	}
+}
+
+func TestShuffleCommitRevealVerifies(t *testing.T) {
+	game := NewGame("test-room", DefaultConfig())
+	game.AddPlayer(NewPlayer("p1", "Player 1", 1000))
+	game.AddPlayer(NewPlayer("p2", "Player 2", 1000))
+	game.SetClientSeed("p1", "lucky")
+
+	var commit ShuffleRecord
+	var result *HandResult
+	game.OnEvent = func(e Event) {
+		switch d := e.Data.(type) {
+		case HandStarted:
+			commit = d.Shuffle
+		case HandEnded:
+			result = &d.Result
+		}
+	}
+	game.StartHand()
+
+	if commit.SeedHash == "" || commit.ServerSeed != "" {
+		t.Fatalf("Commit should publish only the seed hash, got %+v", commit)
+	}
+	if commit.ClientSeeds[0].Seed != "lucky" && commit.ClientSeeds[1].Seed != "lucky" {
+		t.Errorf("Client seed should be mixed in, got %+v", commit.ClientSeeds)
+	}
+	dealt := game.Deck.Cards()
+
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionFold, 0)
+	if result == nil || result.Shuffle == nil || result.Shuffle.ServerSeed == "" {
+		t.Fatal("Server seed should be revealed when the hand ends")
+	}
+	if result.Shuffle.SeedHash != commit.SeedHash || result.HandID != commit.HandID {
+		t.Error("Revealed record should match the commitment")
+	}
+
+	deck, err := DeriveDeck(*result.Shuffle)
+	if err != nil {
+		t.Fatalf("DeriveDeck failed: %v", err)
+	}
+	for i, c := range deck {
+		if dealt[i] != c {
+			t.Fatalf("Derived deck differs from dealt deck at %d", i)
+		}
+	}
+
+	tampered := *result.Shuffle
+	tampered.ServerSeed += "0"
+	if _, err := DeriveDeck(tampered); err == nil {
+		t.Error("Seed not matching the commitment should be rejected")
+	}
+}
+
+func TestNextSeedIsCommittedBeforeClientSeeds(t *testing.T) {
+	game := NewGame("test-room", DefaultConfig())
+	game.AddPlayer(NewPlayer("p1", "Player 1", 1000))
+	game.AddPlayer(NewPlayer("p2", "Player 2", 1000))
+
+	committed := game.NextSeedHash
+	if committed == "" {
+		t.Fatal("A new game should commit to its first server seed")
+	}
+	game.SetClientSeed("p1", "lucky")
+	game.StartHand()
+	if game.Shuffle.SeedHash != committed {
+		t.Errorf("Expected the hand to use the committed seed %s, got %s", committed, game.Shuffle.SeedHash)
+	}
+	if game.NextSeedHash != "" {
+		t.Errorf("Expected no next seed committed during the hand, got %s", game.NextSeedHash)
+	}
+
+	var ended HandEnded
+	game.OnEvent = func(e Event) {
+		if d, ok := e.Data.(HandEnded); ok {
+			ended = d
+		}
+	}
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionFold, 0)
+	if ended.NextSeedHash == "" || ended.NextSeedHash != game.NextSeedHash {
+		t.Fatalf("Expected the hand's end to publish the next commitment, got %q", ended.NextSeedHash)
+	}
+	if ended.NextSeedHash == committed {
+		t.Error("Expected a fresh server seed for the next hand")
+	}
+
+	snapshot, err := game.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	restored, err := RestoreGame(snapshot)
+	if err != nil {
+		t.Fatalf("RestoreGame failed: %v", err)
+	}
+	restored.SetClientSeed("p2", "late")
+	restored.StartHand()
+	if restored.Shuffle.SeedHash != ended.NextSeedHash {
+		t.Errorf("Expected the next hand to use the seed committed when the last ended, got %s", restored.Shuffle.SeedHash)
+	}
+}
+
+func TestScenarioAllInRunOutWithWheel(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`{
+		"name": "short stack wheel",
+		"button": 0,
+		"seats": [
+			{"id": "a", "chips": 100, "hole": ["Ah", "2d"]},
+			{"id": "b", "chips": 500, "hole": ["Kh", "Kd"]},
+			{"id": "c", "chips": 500, "hole": ["Qs", "Qc"]}
+		],
+		"board": ["3c", "4d", "5h", "9s", "Jc"],
+		"actions": [
+			{"player": "a", "action": "all_in"},
+			{"player": "b", "action": "all_in"},
+			{"player": "c", "action": "call"}
+		],
+		"payouts": {"a": 300, "b": 800},
+		"chips": {"a": 300, "b": 800, "c": 0}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseScenario failed: %v", err)
+	}
+	if _, err := scenario.Run(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScenarioBoardPlaysSplit(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`{
+		"name": "royal on board",
+		"button": 1,
+		"seats": [
+			{"id": "a", "chips": 1000, "hole": ["2c", "3d"]},
+			{"id": "b", "chips": 1000, "hole": ["4c", "5d"]}
+		],
+		"board": ["Ah", "Kh", "Qh", "Jh", "Th"],
+		"actions": [
+			{"player": "b", "action": "raise", "amount": 30, "reject": true},
+			{"player": "b", "action": "call"},
+			{"player": "a", "action": "check"},
+			{"player": "a", "action": "check"},
+			{"player": "b", "action": "check"},
+			{"player": "a", "action": "check"},
+			{"player": "b", "action": "check"},
+			{"player": "a", "action": "check"},
+			{"player": "b", "action": "check"}
+		],
+		"payouts": {"a": 20, "b": 20}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseScenario failed: %v", err)
+	}
+	if _, err := scenario.Run(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEvaluateMatchesEvaluateHandForAllFiveCardHands(t *testing.T) {
+	deck := NewDeck().Cards()
+	hand := make([]Card, 5)
+	for a := 0; a < 52; a++ {
+		for b := a + 1; b < 52; b++ {
+			for c := b + 1; c < 52; c++ {
+				for d := c + 1; d < 52; d++ {
+					for e := d + 1; e < 52; e++ {
+						hand[0], hand[1], hand[2], hand[3], hand[4] = deck[a], deck[b], deck[c], deck[d], deck[e]
+						got := Evaluate(hand)
+						want := evaluateFiveCards(hand, false).value()
+						if got != want {
+							t.Fatalf("Evaluate(%v) = %x, want %x", hand, got, want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEvaluateMatchesEvaluateHandForSampledSevenCardHands(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	deck := NewDeck().Cards()
+	for i := 0; i < 10000; i++ {
+		rng.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+		for _, n := range []int{6, 7} {
+			got := Evaluate(deck[:n])
+			want := EvaluateHand(deck[:2], deck[2:n]).value()
+			if got != want {
+				t.Fatalf("Evaluate(%v) = %x, want %x", deck[:n], got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkEvaluateHandSevenCards(b *testing.B) {
+	cards := benchmarkHands(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hand := cards[i%len(cards)]
+		EvaluateHand(hand[:2], hand[2:])
+	}
+}
+
+func BenchmarkEvaluateSevenCards(b *testing.B) {
+	cards := benchmarkHands(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Evaluate(cards[i%len(cards)])
+	}
+}
+
+func BenchmarkEvaluateFiveCards(b *testing.B) {
+	cards := benchmarkHands(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Evaluate(cards[i%len(cards)][:5])
+	}
+}
+
+func benchmarkHands(n int) [][]Card {
+	rng := rand.New(rand.NewSource(1))
+	deck := NewDeck().Cards()
+	hands := make([][]Card, n)
+	for i := range hands {
+		rng.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+		hands[i] = append([]Card{}, deck[:7]...)
+	}
+	return hands
+}
+
+func TestScenarioRunItTwiceSplitsEachPot(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`{
+		"name": "run it twice",
+		"button": 0,
+		"runIt": 2,
+		"seats": [
+			{"id": "a", "chips": 1000, "hole": ["Ah", "Ad"]},
+			{"id": "b", "chips": 1000, "hole": ["Kh", "Kd"]}
+		],
+		"board": ["2c", "7s", "9d", "Kc", "3h"],
+		"actions": [
+			{"player": "a", "action": "all_in"},
+			{"player": "b", "action": "call"}
+		],
+		"payouts": {"a": 1000, "b": 1000}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseScenario failed: %v", err)
+	}
+	result, err := scenario.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Runs) != 2 {
+		t.Fatalf("Expected 2 runs, got %d", len(result.Runs))
+	}
+	if result.Runs[0].Pots[0].Winners["b"] != 1000 || result.Runs[1].Pots[0].Winners["a"] != 1000 {
+		t.Errorf("Kings should win the first board and the ace flush the second, got %+v", result.Runs)
+	}
+	if result.Runs[0].Board[0] == result.Runs[1].Board[0] {
+		t.Error("Each run should deal fresh cards")
+	}
+}
+
+func TestRunItOfferDeclinedRunsOnce(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxRuns = 3
+	game := newHeadsUpGame(config)
+
+	var result *HandResult
+	game.OnEvent = func(e Event) {
+		if ended, ok := e.Data.(HandEnded); ok {
+			result = &ended.Result
+		}
+	}
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionAllIn, 0)
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionCall, 0)
+
+	offer := game.RunItOffer
+	if offer == nil || offer.MaxRuns != 3 {
+		t.Fatalf("Expected a run it offer for up to 3 runs, got %+v", offer)
+	}
+	if err := game.ProcessAction("p1", ActionCheck, 0); err == nil {
+		t.Error("Nobody should act while the offer is open")
+	}
+
+	game.RespondRunIt(offer.PlayerIDs[0], 3)
+	if result != nil {
+		t.Fatal("Hand should wait for every player")
+	}
+	game.RespondRunIt(offer.PlayerIDs[1], 1)
+	if result == nil || len(result.Runs) != 0 || len(game.CommunityCards) != 5 {
+		t.Error("A single decline should run the board once")
+	}
+}
+
+func newTableGame(config GameConfig, players int) *Game {
+	game := NewGame("test-room", config)
+	for i := 0; i < players; i++ {
+		id := fmt.Sprintf("p%d", i)
+		game.AddPlayer(NewPlayer(id, id, 1000))
+	}
+	return game
+}
+
+func foldToWinner(game *Game) {
+	for game.Phase != PhaseFinished {
+		game.ProcessAction(game.GetCurrentPlayer().ID, ActionFold, 0)
+	}
+}
+
+func TestPerPlayerAnteIsNotPartOfTheBet(t *testing.T) {
+	config := DefaultConfig()
+	config.Ante = 5
+	game := newTableGame(config, 3)
+	game.StartHand()
+
+	sb := game.getPlayerBySeat(game.SmallBlindSeat)
+	if game.CurrentBet != 20 || sb.CurrentBet != 10 || sb.TotalBetInHand != 15 {
+		t.Errorf("Ante should go in the pot but not the bet, got currentBet=%d sb bet=%d total=%d",
+			game.CurrentBet, sb.CurrentBet, sb.TotalBetInHand)
+	}
+}
+
+func TestBigBlindAnte(t *testing.T) {
+	config := DefaultConfig()
+	config.AnteMode = AnteBigBlind
+	game := newTableGame(config, 3)
+	game.StartHand()
+
+	bb := game.getPlayerBySeat(game.BigBlindSeat)
+	if bb.Chips != 960 || game.DeadMoney != 20 || game.CurrentBet != 20 {
+		t.Errorf("Big blind should post the blind and one ante, got chips=%d dead=%d bet=%d", bb.Chips, game.DeadMoney, game.CurrentBet)
+	}
+}
+
+func TestUTGStraddle(t *testing.T) {
+	config := DefaultConfig()
+	config.Straddle = StraddleUTG
+	game := newTableGame(config, 4)
+	game.SetStraddle("p3", true)
+	game.StartHand()
+
+	if game.StraddleSeat != 3 || game.CurrentBet != 40 {
+		t.Fatalf("UTG should straddle to 40, got seat=%d bet=%d", game.StraddleSeat, game.CurrentBet)
+	}
+	if game.CurrentPlayerSeat != 0 {
+		t.Errorf("Action should start left of the straddle, got seat %d", game.CurrentPlayerSeat)
+	}
+	if min, _ := game.GetRaiseLimits("p0"); min != 80 {
+		t.Errorf("Minimum raise should be to 80, got %d", min)
+	}
+
+	game.ProcessAction("p0", ActionCall, 0)
+	game.ProcessAction("p1", ActionCall, 0)
+	game.ProcessAction("p2", ActionCall, 0)
+	if game.Phase != PhasePreflop || game.CurrentPlayerSeat != 3 {
+		t.Errorf("Straddler should get the option, got phase=%s seat=%d", game.Phase, game.CurrentPlayerSeat)
+	}
+}
+
+func TestMissedBlindsCollectedOnReturn(t *testing.T) {
+	game := newTableGame(DefaultConfig(), 4)
+	game.StartHand()
+	if game.DealerSeat != 0 || game.SmallBlindSeat != 1 || game.BigBlindSeat != 2 {
+		t.Fatalf("Unexpected first hand positions %d/%d/%d", game.DealerSeat, game.SmallBlindSeat, game.BigBlindSeat)
+	}
+	foldToWinner(game)
+
+	game.SitOut("p3")
+	game.StartHand()
+	if game.DealerSeat != 1 || game.SmallBlindSeat != 2 || game.BigBlindSeat != 0 {
+		t.Errorf("Big blind should skip the empty seat, got %d/%d/%d", game.DealerSeat, game.SmallBlindSeat, game.BigBlindSeat)
+	}
+	p3 := game.getPlayerByID("p3")
+	if !p3.MissedBigBlind || !p3.MissedSmallBlind {
+		t.Error("Sitting out through the big blind should be recorded")
+	}
+	foldToWinner(game)
+
+	game.SitIn("p3")
+	chips := p3.Chips
+	game.StartHand()
+	if game.DealerSeat != 2 || game.SmallBlindSeat != 0 || game.BigBlindSeat != 1 {
+		t.Errorf("Unexpected positions after return %d/%d/%d", game.DealerSeat, game.SmallBlindSeat, game.BigBlindSeat)
+	}
+	if p3.Chips != chips-30 || p3.CurrentBet != 20 || game.DeadMoney != 10 {
+		t.Errorf("Returning player should post 20 live and 10 dead, got chips=%d bet=%d dead=%d",
+			chips-p3.Chips, p3.CurrentBet, game.DeadMoney)
+	}
+	if p3.MissedBigBlind || p3.MissedSmallBlind {
+		t.Error("Missed blinds should be cleared once paid")
+	}
+}
+
+func TestDeadSmallBlindWhenBigBlindLeaves(t *testing.T) {
+	game := newTableGame(DefaultConfig(), 4)
+	game.StartHand()
+	foldToWinner(game)
+
+	game.RemovePlayer("p2")
+	game.StartHand()
+	if game.DealerSeat != 1 || game.SmallBlindSeat != 2 || game.BigBlindSeat != 3 {
+		t.Errorf("Small blind should be dead on the empty seat, got %d/%d/%d", game.DealerSeat, game.SmallBlindSeat, game.BigBlindSeat)
+	}
+	if game.getPlayerBySeat(1).CurrentBet != 0 {
+		t.Error("Button should not post when the small blind is dead")
+	}
+}
+
+func TestEventLogReplaysToLiveState(t *testing.T) {
+	config := DefaultConfig()
+	config.Ante = 5
+	config.Straddle = StraddleUTG
+	game := newTableGame(config, 6)
+	for i, p := range game.Players {
+		p.Chips = int64(200 + 300*i)
+		p.Straddle = i%2 == 0
+	}
+
+	rng := rand.New(rand.NewSource(12))
+	for hand := 0; hand < 60; hand++ {
+		for _, p := range game.Players {
+			if p.Chips == 0 {
+				p.Chips = 1000
+			}
+		}
+		switch hand % 7 {
+		case 2:
+			game.SitOut("p3")
+		case 5:
+			game.SitIn("p3")
+		}
+		if err := game.StartHand(); err != nil {
+			t.Fatalf("Hand %d: StartHand failed: %v", hand, err)
+		}
+		assertReplayMatches(t, game, fmt.Sprintf("hand %d start", hand))
+
+		for step := 0; game.Phase != PhaseFinished; step++ {
+			p := game.GetCurrentPlayer()
+			if step == 3 && hand%10 == 4 {
+				game.UseTimeBank(p.ID)
+			}
+			action, amount := randomAction(rng, game, p)
+			if err := game.ProcessAction(p.ID, action, amount); err != nil {
+				game.ProcessAction(p.ID, ActionFold, 0)
+			}
+			assertReplayMatches(t, game, fmt.Sprintf("hand %d step %d", hand, step))
+		}
+	}
+}
+
+func TestEventLogSequenceIsContiguous(t *testing.T) {
+	game := newTableGame(DefaultConfig(), 3)
+	var events []Event
+	game.OnEvent = func(e Event) { events = append(events, e) }
+
+	game.StartHand()
+	game.RemovePlayer(game.getPlayerBySeat(game.BigBlindSeat).ID)
+	foldToWinner(game)
+	game.StartHand()
+
+	for i := 1; i < len(events); i++ {
+		if events[i].Seq != events[i-1].Seq+1 {
+			t.Fatalf("Expected seq %d after %d, got %d", events[i-1].Seq+1, events[i-1].Seq, events[i].Seq)
+		}
+	}
+	if events[0].Type != EventHandStarted {
+		t.Errorf("Expected the log to open with hand_started, got %s", events[0].Type)
+	}
+	if log := game.Events(0); log[0].Type != EventHandStarted || log[0].HandNumber != 2 {
+		t.Errorf("Expected Events to hold only the current hand, got %+v", log[0])
+	}
+}
+
+func randomAction(rng *rand.Rand, game *Game, p *Player) (ActionType, int64) {
+	switch roll := rng.Intn(20); {
+	case roll < 2:
+		return ActionFold, 0
+	case roll < 12:
+		if p.CurrentBet >= game.CurrentBet {
+			return ActionCheck, 0
+		}
+		return ActionCall, 0
+	case roll < 18:
+		minTotal, maxTotal, ok := game.raiseBounds(p)
+		if !ok {
+			return ActionCall, 0
+		}
+		return ActionRaise, minTotal + rng.Int63n(maxTotal-minTotal+1)
+	default:
+		return ActionAllIn, 0
+	}
+}
+
+// assertReplayMatches rebuilds the hand from its log, after a round trip
+// through JSON, and checks it against the live game.
+func assertReplayMatches(t *testing.T, live *Game, step string) {
+	t.Helper()
+	data, err := json.Marshal(live.Events(0))
+	if err != nil {
+		t.Fatalf("%s: marshal events: %v", step, err)
+	}
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		t.Fatalf("%s: unmarshal events: %v", step, err)
+	}
+
+	replayed, err := Replay(events)
+	if err != nil {
+		t.Fatalf("%s: replay failed: %v", step, err)
+	}
+	replayed.ID, replayed.RoomID = live.ID, live.RoomID
+
+	want, err := json.Marshal(live)
+	if err != nil {
+		t.Fatalf("%s: marshal game: %v", step, err)
+	}
+	got, err := json.Marshal(replayed)
+	if err != nil {
+		t.Fatalf("%s: marshal replayed game: %v", step, err)
+	}
+	if string(want) != string(got) {
+		t.Fatalf("%s: replayed state differs\nlive:     %s\nreplayed: %s", step, want, got)
+	}
+}
+
+func TestSnapshotRestoreContinuesHand(t *testing.T) {
+	config := DefaultConfig()
+	config.Ante = 5
+	rng := rand.New(rand.NewSource(3))
+
+	for hand := 0; hand < 20; hand++ {
+		live := newTableGame(config, 4)
+		live.SetClientSeed("p1", "seed")
+		live.StartHand()
+		for step := 0; step < hand%6 && live.Phase != PhaseFinished; step++ {
+			p := live.GetCurrentPlayer()
+			if p.CurrentBet >= live.CurrentBet {
+				live.ProcessAction(p.ID, ActionCheck, 0)
+			} else {
+				live.ProcessAction(p.ID, ActionCall, 0)
+			}
+		}
+
+		snapshot, err := live.Snapshot()
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		data, _ := json.Marshal(snapshot)
+		var decoded Snapshot
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal snapshot failed: %v", err)
+		}
+		restored, err := RestoreGame(&decoded)
+		if err != nil {
+			t.Fatalf("RestoreGame failed: %v", err)
+		}
+
+		for step := 0; live.Phase != PhaseFinished; step++ {
+			p := live.GetCurrentPlayer()
+			action, amount := randomAction(rng, live, p)
+			liveErr := live.ProcessAction(p.ID, action, amount)
+			restoredErr := restored.ProcessAction(p.ID, action, amount)
+			if (liveErr == nil) != (restoredErr == nil) {
+				t.Fatalf("Hand %d step %d: live returned %v, restored %v", hand, step, liveErr, restoredErr)
+			}
+			if liveErr != nil {
+				live.ProcessAction(p.ID, ActionFold, 0)
+				restored.ProcessAction(p.ID, ActionFold, 0)
+			}
+		}
+
+		// Each draws its own seed for the next hand.
+		live.ActionDeadline, restored.ActionDeadline = time.Time{}, time.Time{}
+		live.NextSeedHash, restored.NextSeedHash = "", ""
+		want, _ := json.Marshal(live)
+		got, _ := json.Marshal(restored)
+		if string(want) != string(got) {
+			t.Fatalf("Hand %d: restored game finished differently\nlive:     %s\nrestored: %s", hand, want, got)
+		}
+		if restored.Shuffle.ServerSeed != live.Shuffle.ServerSeed || restored.Events(0)[0].Seq != live.Events(0)[0].Seq {
+			t.Errorf("Hand %d: restored game lost its seed or event log", hand)
+		}
+	}
+}
+
+func TestRestoreRejectsUnknownVersion(t *testing.T) {
+	game := newTableGame(DefaultConfig(), 2)
+	snapshot, _ := game.Snapshot()
+	snapshot.Version = SnapshotVersion + 1
+	if _, err := RestoreGame(snapshot); err == nil {
+		t.Error("Expected a snapshot from a newer version to be refused")
+	}
+}
+
+func TestHoleCardsVisibleOnlyToOwnerUntilShown(t *testing.T) {
+	game := newHeadsUpGame(DefaultConfig())
+	p1, p2 := game.Players[0], game.Players[1]
+	if len(p1.VisibleCards(p1.ID)) != 2 || len(p1.VisibleCards(p2.ID)) != 0 || len(p1.VisibleCards("")) != 0 {
+		t.Error("Hole cards should only be visible to their owner before showdown")
+	}
+
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionAllIn, 0)
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionCall, 0)
+	if len(p1.VisibleCards(p2.ID)) != 2 || len(p2.VisibleCards("")) != 2 {
+		t.Error("Expected both hands face up once all in")
+	}
+
+	p1.Chips, p2.Chips = 1000, 1000
+	game.StartHand()
+	if len(p1.VisibleCards(p2.ID)) != 0 {
+		t.Error("Expected cards to be hidden again in the next hand")
+	}
+	foldToWinner(game)
+	if len(p1.ShownCards) != 0 || len(p2.ShownCards) != 0 {
+		t.Error("Expected no cards shown when the hand is won without a showdown")
+	}
+}
+
+func TestShowdownOrderAndMuck(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`{
+		"name": "river bettor shows first, beaten hand mucks",
+		"button": 0,
+		"seats": [
+			{"id": "a", "chips": 1000, "hole": ["2c", "3d"]},
+			{"id": "b", "chips": 1000, "hole": ["Ac", "Ad"]},
+			{"id": "c", "chips": 1000, "hole": ["Kc", "Kd"]}
+		],
+		"board": ["9h", "7s", "4d", "Jc", "Qh"],
+		"actions": [
+			{"player": "a", "action": "call"},
+			{"player": "b", "action": "call"},
+			{"player": "c", "action": "check"},
+			{"player": "b", "action": "check"},
+			{"player": "c", "action": "check"},
+			{"player": "a", "action": "check"},
+			{"player": "b", "action": "check"},
+			{"player": "c", "action": "check"},
+			{"player": "a", "action": "check"},
+			{"player": "b", "action": "check"},
+			{"player": "c", "action": "raise", "amount": 40},
+			{"player": "a", "action": "call"},
+			{"player": "b", "action": "call"}
+		],
+		"payouts": {"b": 180}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseScenario failed: %v", err)
+	}
+	result, err := scenario.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make([]string, 0, len(result.Showdown))
+	for _, h := range result.Showdown {
+		order = append(order, h.PlayerID)
+	}
+	if fmt.Sprint(order) != "[c a b]" {
+		t.Fatalf("Expected show order [c a b], got %v", order)
+	}
+	if result.Showdown[0].Mucked || !result.Showdown[1].Mucked || result.Showdown[2].Mucked {
+		t.Errorf("Expected only a to muck, got %+v", result.Showdown)
+	}
+	if result.Showdown[1].Cards != nil {
+		t.Error("Mucked cards should not be revealed")
+	}
+	if hand := result.Showdown[2].Hand; hand == nil || hand.Type != OnePair || len(hand.Cards) != 5 {
+		t.Errorf("Expected the winning pair of aces with its five cards, got %+v", hand)
+	}
+}
+
+func TestUncontestedWinnerMayShow(t *testing.T) {
+	game := newHeadsUpGame(DefaultConfig())
+	loser := game.GetCurrentPlayer()
+	if err := game.ShowCards(loser.ID, []int{0}); err == nil {
+		t.Error("Expected showing mid-hand to be refused")
+	}
+	foldToWinner(game)
+
+	var winner *Player
+	for _, p := range game.Players {
+		if p != loser {
+			winner = p
+		}
+	}
+	if err := game.ShowCards(loser.ID, []int{0}); err == nil {
+		t.Error("Expected the folded player to be refused")
+	}
+	if err := game.ShowCards(winner.ID, []int{1}); err != nil {
+		t.Fatalf("ShowCards failed: %v", err)
+	}
+	if len(winner.ShownCards) != 1 || winner.ShownCards[0] != winner.HoleCards[1] {
+		t.Errorf("Expected only the second card shown, got %v", winner.ShownCards)
+	}
+	game.ShowCards(winner.ID, []int{0, 1})
+	if len(winner.ShownCards) != 2 {
+		t.Errorf("Expected both cards shown, got %v", winner.ShownCards)
+	}
+}
+
+type testStep struct {
+	action ActionType
+	amount int64
+}
+
+// startFourHanded deals a four-handed hand and sets stacks by preflop
+// position, 0 for under the gun through 3 for the big blind, counting only
+// the chips behind.
+func startFourHanded(config GameConfig, stacks map[int]int64) (*Game, []*Player) {
+	game := newTableGame(config, 4)
+	game.StartHand()
+	positions := []*Player{
+		game.GetCurrentPlayer(),
+		game.getPlayerBySeat(game.DealerSeat),
+		game.getPlayerBySeat(game.SmallBlindSeat),
+		game.getPlayerBySeat(game.BigBlindSeat),
+	}
+	for pos, chips := range stacks {
+		positions[pos].Chips = chips
+	}
+	return game, positions
+}
+
+func playSteps(t *testing.T, game *Game, steps []testStep) {
+	t.Helper()
+	for i, s := range steps {
+		p := game.GetCurrentPlayer()
+		if err := game.ProcessAction(p.ID, s.action, s.amount); err != nil {
+			t.Fatalf("step %d: %s %v %d failed: %v", i, p.ID, s.action, s.amount, err)
+		}
+	}
+}
+
+func TestReopeningAfterShortAllIn(t *testing.T) {
+	tests := []struct {
+		name      string
+		structure BettingStructure
+		stacks    map[int]int64
+		steps     []testStep
+		canRaise  bool
+		minTotal  int64
+	}{
+		{
+			name:   "short all-in does not reopen for a caller",
+			stacks: map[int]int64{1: 30},
+			steps:  []testStep{{ActionCall, 0}, {ActionAllIn, 0}, {ActionCall, 0}, {ActionCall, 0}},
+		},
+		{
+			name:     "short all-in lets players yet to act raise",
+			stacks:   map[int]int64{1: 30},
+			steps:    []testStep{{ActionCall, 0}, {ActionAllIn, 0}},
+			canRaise: true,
+			minTotal: 50,
+		},
+		{
+			name:     "full all-in raise reopens",
+			stacks:   map[int]int64{1: 40},
+			steps:    []testStep{{ActionCall, 0}, {ActionAllIn, 0}, {ActionCall, 0}, {ActionCall, 0}},
+			canRaise: true,
+			minTotal: 60,
+		},
+		{
+			name:     "short all-ins that add up to a full raise reopen",
+			stacks:   map[int]int64{1: 30, 2: 35},
+			steps:    []testStep{{ActionCall, 0}, {ActionAllIn, 0}, {ActionAllIn, 0}, {ActionCall, 0}},
+			canRaise: true,
+			minTotal: 65,
+		},
+		{
+			name:     "short all-in keeps the last full raise as the minimum",
+			stacks:   map[int]int64{1: 70},
+			steps:    []testStep{{ActionRaise, 60}, {ActionAllIn, 0}},
+			canRaise: true,
+			minTotal: 110,
+		},
+		{
+			name:     "short raise for a whole stack keeps the minimum",
+			stacks:   map[int]int64{1: 70},
+			steps:    []testStep{{ActionRaise, 60}, {ActionRaise, 70}},
+			canRaise: true,
+			minTotal: 110,
+		},
+		{
+			name:   "raiser cannot re-raise a short all-in",
+			stacks: map[int]int64{1: 70},
+			steps:  []testStep{{ActionRaise, 60}, {ActionAllIn, 0}, {ActionFold, 0}, {ActionFold, 0}},
+		},
+		{
+			name:      "half a bet all-in reopens in fixed limit",
+			structure: FixedLimit,
+			stacks:    map[int]int64{1: 30},
+			steps:     []testStep{{ActionCall, 0}, {ActionAllIn, 0}, {ActionFold, 0}, {ActionFold, 0}},
+			canRaise:  true,
+			minTotal:  50,
+		},
+		{
+			name:      "less than half a bet does not reopen in fixed limit",
+			structure: FixedLimit,
+			stacks:    map[int]int64{1: 29},
+			steps:     []testStep{{ActionCall, 0}, {ActionAllIn, 0}, {ActionFold, 0}, {ActionFold, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.BettingStructure = tt.structure
+			game, _ := startFourHanded(config, tt.stacks)
+			playSteps(t, game, tt.steps)
+
+			current := game.GetCurrentPlayer()
+			if game.Phase != PhasePreflop || current == nil {
+				t.Fatalf("Expected preflop action to continue, got phase %v", game.Phase)
+			}
+			minTotal, _ := game.GetRaiseLimits(current.ID)
+			if tt.canRaise {
+				if minTotal != tt.minTotal {
+					t.Errorf("Expected minimum raise to %d, got %d", tt.minTotal, minTotal)
+				}
+				return
+			}
+			if minTotal != 0 {
+				t.Errorf("Expected no raise allowed, got minimum %d", minTotal)
+			}
+			if err := game.ProcessAction(current.ID, ActionRaise, 1000); err == nil {
+				t.Error("Expected raise to be refused")
+			}
+			if err := game.ProcessAction(current.ID, ActionAllIn, 0); err == nil {
+				t.Error("Expected all-in to be refused")
+			}
+			if err := game.ProcessAction(current.ID, ActionCall, 0); err != nil {
+				t.Errorf("Expected call to be allowed: %v", err)
+			}
+		})
+	}
+}
+
+func TestUncalledBetReturned(t *testing.T) {
+	tests := []struct {
+		name     string
+		stacks   map[int]int64
+		steps    []testStep
+		returnTo int // preflop position
+		returned int64
+		pot      int64
+	}{
+		{
+			name:     "walk returns the big blind's uncalled half",
+			steps:    []testStep{{ActionFold, 0}, {ActionFold, 0}, {ActionFold, 0}},
+			returnTo: 3,
+			returned: 10,
+			pot:      20,
+		},
+		{
+			name:     "raise nobody calls",
+			steps:    []testStep{{ActionRaise, 60}, {ActionFold, 0}, {ActionFold, 0}, {ActionFold, 0}},
+			returnTo: 0,
+			returned: 40,
+			pot:      50,
+		},
+		{
+			name:     "shove over a shorter all-in",
+			stacks:   map[int]int64{1: 300},
+			steps:    []testStep{{ActionAllIn, 0}, {ActionCall, 0}, {ActionFold, 0}, {ActionFold, 0}},
+			returnTo: 0,
+			returned: 700,
+			pot:      630,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, positions := startFourHanded(DefaultConfig(), tt.stacks)
+			var chips int64
+			for _, p := range game.Players {
+				chips += p.Chips + p.TotalBetInHand
+			}
+			playSteps(t, game, tt.steps)
+			if game.Phase != PhaseFinished {
+				t.Fatalf("Expected the hand to finish, got phase %v", game.Phase)
+			}
+
+			var returned []BetReturned
+			var result HandResult
+			for _, e := range game.Events(0) {
+				switch d := e.Data.(type) {
+				case BetReturned:
+					returned = append(returned, d)
+				case HandEnded:
+					result = d.Result
+				}
+			}
+			want := positions[tt.returnTo].ID
+			if len(returned) != 1 || returned[0].PlayerID != want || returned[0].Amount != tt.returned {
+				t.Errorf("Expected %d returned to %s, got %+v", tt.returned, want, returned)
+			}
+			if pot := result.Pots; len(pot) != 1 || pot[0].Amount != tt.pot {
+				t.Errorf("Expected a single pot of %d, got %+v", tt.pot, pot)
+			}
+
+			var after int64
+			for _, p := range game.Players {
+				after += p.Chips
+			}
+			if after != chips {
+				t.Errorf("Expected %d chips in play, got %d", chips, after)
+			}
+		})
+	}
+}
+
+func TestLegalActions(t *testing.T) {
+	game, positions := startFourHanded(DefaultConfig(), map[int]int64{1: 30})
+	utg := positions[0]
+
+	if actions, _ := game.LegalActions(positions[1].ID); actions != nil {
+		t.Errorf("Expected no actions out of turn, got %+v", actions)
+	}
+
+	actions, deadline := game.LegalActions(utg.ID)
+	expected := []LegalAction{
+		{Action: ActionFold},
+		{Action: ActionCall, Min: 20, Max: 20},
+		{Action: ActionRaise, Min: 40, Max: 1000},
+		{Action: ActionAllIn, Min: 1000, Max: 1000},
+	}
+	if fmt.Sprint(actions) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actions)
+	}
+	if !deadline.Equal(game.ActionDeadline) {
+		t.Errorf("Expected deadline %v, got %v", game.ActionDeadline, deadline)
+	}
+
+	// A short all-in leaves the caller able only to call the difference.
+	playSteps(t, game, []testStep{{ActionCall, 0}, {ActionAllIn, 0}, {ActionCall, 0}, {ActionCall, 0}})
+	actions, _ = game.LegalActions(utg.ID)
+	expected = []LegalAction{
+		{Action: ActionFold},
+		{Action: ActionCall, Min: 10, Max: 10},
+	}
+	if fmt.Sprint(actions) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actions)
+	}
+}
+
+func TestCheckInvariantsCatchesChipsFromNowhere(t *testing.T) {
+	game := newTableGame(DefaultConfig(), 3)
+	game.StartHand()
+	if err := game.CheckInvariants(); err != nil {
+		t.Fatalf("Expected a fresh hand to pass, got %v", err)
+	}
+
+	p := game.GetCurrentPlayer()
+	if err := game.AddChips(p.ID, 500); err == nil {
+		t.Error("Expected topping up mid-hand to be refused")
+	}
+	p.Chips += 500
+	if err := game.CheckInvariants(); err == nil {
+		t.Error("Expected chips added outside the log to be caught")
+	}
+	p.Chips -= 500
+
+	foldToWinner(game)
+	if err := game.AddChips(p.ID, 500); err != nil {
+		t.Fatalf("AddChips between hands failed: %v", err)
+	}
+	if err := game.CheckInvariants(); err != nil {
+		t.Errorf("Expected a logged top-up to pass, got %v", err)
+	}
+
+	game.Players[1].SeatIndex = game.Players[0].SeatIndex
+	if err := game.CheckInvariants(); err == nil {
+		t.Error("Expected a shared seat to be caught")
+	}
+}
+
+func TestHandDescriptions(t *testing.T) {
+	tests := []struct {
+		hole, board string
+		en, zh      string
+	}{
+		{"Ah Kd", "Qs 9c 4h 3d 2s", "High Card, Ace-King-Queen-Nine-Four", "高牌，A-K-Q-9-4"},
+		{"Kh Kd", "As Ts 4h 3d 2c", "One Pair, Kings, Ace-Ten-Four kickers", "一对 K，踢脚 A-10-4"},
+		{"Kh 9d", "Ks 9s Ah 3d 2c", "Two Pair, Kings and Nines, Ace kicker", "两对，K 和 9，踢脚 A"},
+		{"7h 7d", "7s Qs Ah 3d 2c", "Three of a Kind, Sevens, Ace-Queen kickers", "三条 7，踢脚 A-Q"},
+		{"Ah 2d", "3s 4s 5h Kd Kc", "Straight, Five high", "顺子，5 高"},
+		{"Ah Jh", "8h 6h 2h Kd Kc", "Flush, Ace-Jack-Eight-Six-Two", "同花，A-J-8-6-2"},
+		{"Kh Kd", "Ks 9s 9h 3d 2c", "Full House, Kings full of Nines", "葫芦，K 带 9"},
+		{"Qh Qd", "Qs Qc Ah 3d 2c", "Four of a Kind, Queens, Ace kicker", "四条 Q，踢脚 A"},
+		{"9h 8h", "7h 6h 5h Kd 2c", "Straight Flush, Nine high", "同花顺，9 高"},
+		{"Ah Kh", "Qh Jh Th 3d 2c", "Royal Flush", "皇家同花顺"},
+	}
+
+	parse := func(s string) []Card {
+		var cards []Card
+		for _, str := range strings.Fields(s) {
+			c, err := ParseCard(str)
+			if err != nil {
+				t.Fatalf("ParseCard(%q): %v", str, err)
+			}
+			cards = append(cards, c)
+		}
+		return cards
+	}
+
+	for _, tt := range tests {
+		hand := EvaluateHand(parse(tt.hole), parse(tt.board))
+		if got := hand.Describe(LocaleEnglish); got != tt.en {
+			t.Errorf("Expected %q, got %q", tt.en, got)
+		}
+		if got := hand.Describe(ParseLocale("zh-CN")); got != tt.zh {
+			t.Errorf("Expected %q, got %q", tt.zh, got)
+		}
+	}
+}
+
+func callAround(game *Game, phase Phase) {
+	for game.Phase == phase {
+		game.ProcessAction(game.GetCurrentPlayer().ID, ActionCall, 0)
+	}
+}
+
+func TestPineappleDiscard(t *testing.T) {
+	config := DefaultConfig()
+	config.Variant = "pineapple"
+	game := newTableGame(config, 3)
+	game.StartHand()
+
+	for _, p := range game.Players {
+		if len(p.HoleCards) != 3 {
+			t.Fatalf("Expected 3 hole cards for %s, got %d", p.ID, len(p.HoleCards))
+		}
+	}
+	if err := game.Discard("p0", 0); err == nil {
+		t.Error("Expected discarding before the preflop betting closes to fail")
+	}
+
+	callAround(game, PhasePreflop)
+	if game.Phase != PhaseDiscard {
+		t.Fatalf("Expected the discard after preflop, got %s", game.Phase)
+	}
+	if len(game.CommunityCards) != 0 || game.CurrentPlayerSeat != -1 {
+		t.Errorf("Expected no board and nobody to act, got %d cards and seat %d", len(game.CommunityCards), game.CurrentPlayerSeat)
+	}
+	legal, _ := game.LegalActions("p0")
+	if len(legal) != 1 || legal[0].Action != ActionDiscard || legal[0].Max != 2 {
+		t.Errorf("Expected only a discard of card 0-2, got %v", legal)
+	}
+
+	p0 := game.getPlayerByID("p0")
+	thrown := p0.HoleCards[1]
+	if err := game.ProcessAction("p0", ActionDiscard, 1); err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	if len(p0.HoleCards) != 2 || containsCard(p0.VisibleCards("p0"), thrown) {
+		t.Errorf("Expected %v gone from p0's hand, got %v", thrown, p0.HoleCards)
+	}
+	if err := game.Discard("p0", 0); err == nil {
+		t.Error("Expected a second discard to fail")
+	}
+	if err := game.Discard("p1", 3); err == nil {
+		t.Error("Expected discarding a card out of range to fail")
+	}
+	assertReplayMatches(t, game, "after one discard")
+
+	game.Discard("p1", 2)
+	if game.Phase != PhaseDiscard {
+		t.Fatalf("Expected to wait for p2, got %s", game.Phase)
+	}
+	// Leaving is the last discard anyone was waiting on.
+	game.RemovePlayer("p2")
+	if game.Phase != PhaseFlop || len(game.CommunityCards) != 3 {
+		t.Fatalf("Expected the flop once nobody has a card left to throw away, got %s", game.Phase)
+	}
+	if p := game.GetCurrentPlayer(); p == nil || p.State != StateActive {
+		t.Errorf("Expected someone to act on the flop")
+	}
+	assertReplayMatches(t, game, "flop")
+}
+
+func TestCrazyPineappleDiscardsAfterTheFlop(t *testing.T) {
+	config := DefaultConfig()
+	config.Variant = "crazy_pineapple"
+	game := newTableGame(config, 3)
+	game.StartHand()
+
+	callAround(game, PhasePreflop)
+	if game.Phase != PhaseFlop {
+		t.Fatalf("Expected to bet the flop with three cards, got %s", game.Phase)
+	}
+	callAround(game, PhaseFlop)
+	if game.Phase != PhaseDiscard || len(game.CommunityCards) != 3 {
+		t.Fatalf("Expected the discard on the flop, got %s with %d cards", game.Phase, len(game.CommunityCards))
+	}
+
+	if game.ExpireDiscards(game.ActionDeadline.Add(-time.Second)) {
+		t.Error("Expected the discard clock to still be running")
+	}
+	if !game.ExpireDiscards(game.ActionDeadline) {
+		t.Fatal("Expected the discard clock to run out")
+	}
+	if game.Phase != PhaseTurn {
+		t.Fatalf("Expected the turn once everyone timed out, got %s", game.Phase)
+	}
+	for _, p := range game.Players {
+		if len(p.HoleCards) != 2 {
+			t.Errorf("Expected %s to keep 2 cards, got %d", p.ID, len(p.HoleCards))
+		}
+	}
+	assertReplayMatches(t, game, "turn")
+}
+
+func TestPineappleAllInWaitsForTheDiscard(t *testing.T) {
+	config := DefaultConfig()
+	config.Variant = "crazy_pineapple"
+	game := newTableGame(config, 2)
+	game.StartHand()
+
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionAllIn, 0)
+	game.ProcessAction(game.GetCurrentPlayer().ID, ActionCall, 0)
+	if game.Phase != PhaseDiscard || len(game.CommunityCards) != 3 {
+		t.Fatalf("Expected the run-out to stop for the discard on the flop, got %s with %d cards", game.Phase, len(game.CommunityCards))
+	}
+	for _, p := range game.Players {
+		if len(p.ShownCards) != 0 {
+			t.Errorf("Expected %s's cards face down until they discard, got %v", p.ID, p.ShownCards)
+		}
+	}
+	assertReplayMatches(t, game, "discard")
+
+	game.Discard("p0", 0)
+	game.Discard("p1", 0)
+	if game.Phase != PhaseFinished || len(game.CommunityCards) != 5 {
+		t.Fatalf("Expected the board run out after the discard, got %s", game.Phase)
+	}
+	for _, p := range game.Players {
+		if len(p.ShownCards) != 2 {
+			t.Errorf("Expected %s to show the 2 cards kept, got %v", p.ID, p.ShownCards)
+		}
+	}
+	assertReplayMatches(t, game, "end")
+}
+
+func TestUnseatOnlyBetweenHands(t *testing.T) {
+	game := newTableGame(DefaultConfig(), 4)
+	game.StartHand()
+
+	if _, err := game.Unseat("p0"); err == nil {
+		t.Errorf("Expected unseating during a hand to fail")
+	}
+	foldToWinner(game)
+
+	next := game.NextBigBlind()
+	chips := game.getPlayerByID(next).Chips
+	got, err := game.Unseat(next)
+	if err != nil || got != chips {
+		t.Errorf("Expected to unseat %s with %d chips, got %d (%v)", next, chips, got, err)
+	}
+	if len(game.Players) != 3 {
+		t.Errorf("Expected 3 players left, got %d", len(game.Players))
+	}
+
+	next = game.NextBigBlind()
+	game.StartHand()
+	if bb := game.getPlayerBySeat(game.BigBlindSeat); bb == nil || bb.ID != next {
+		t.Errorf("Expected %s in the big blind, got %+v", next, bb)
+	}
+}
+
+func TestNoActionAfterTheHand(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	actions := []ActionType{ActionCheck, ActionCall, ActionAllIn, ActionFold}
+	for hand := 0; hand < 500; hand++ {
+		game := newTableGame(DefaultConfig(), 2+rng.Intn(5))
+		game.StartHand()
+		for game.Phase != PhaseFinished {
+			game.ProcessAction(game.GetCurrentPlayer().ID, actions[rng.Intn(len(actions))], 0)
+		}
+
+		for _, p := range game.Players {
+			for _, action := range actions {
+				if err := game.ProcessAction(p.ID, action, 0); err == nil {
+					t.Fatalf("Expected %s to be refused after the hand, got %v accepted", p.ID, action)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package game
+
+import (
+	"fmt"
+)
+
+// CheckInvariants verifies the bookkeeping no sequence of actions may
+// break: seats and IDs are unique, no stack or bet is negative, pots only
+// name players in the hand and nest inside one another, and the chips of
+// everyone at the table when the hand started add up to what they started
+// with plus any top-ups since.
+func (g *Game) CheckInvariants() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if err := g.checkSeats(); err != nil {
+		return err
+	}
+	if err := g.checkBets(); err != nil {
+		return err
+	}
+	if err := g.checkPots(); err != nil {
+		return err
+	}
+	return g.checkChipTotal()
+}
+
+func (g *Game) inHand() bool {
+	return g.Phase != PhaseWaiting && g.Phase != PhaseFinished
+}
+
+func (g *Game) checkSeats() error {
+	seats := make(map[int]string)
+	ids := make(map[string]bool)
+	for _, p := range g.Players {
+		if ids[p.ID] {
+			return fmt.Errorf("player %s is seated twice", p.ID)
+		}
+		ids[p.ID] = true
+		if p.SeatIndex < 0 || p.SeatIndex >= g.Config.MaxPlayers {
+			return fmt.Errorf("player %s has invalid seat %d", p.ID, p.SeatIndex)
+		}
+		if other, ok := seats[p.SeatIndex]; ok {
+			return fmt.Errorf("players %s and %s share seat %d", other, p.ID, p.SeatIndex)
+		}
+		seats[p.SeatIndex] = p.ID
+	}
+	return nil
+}
+
+func (g *Game) checkBets() error {
+	if g.DeadMoney < 0 {
+		return fmt.Errorf("negative dead money %d", g.DeadMoney)
+	}
+	for _, p := range g.Players {
+		if p.Chips < 0 || p.CurrentBet < 0 || p.TotalBetInHand < 0 {
+			return fmt.Errorf("player %s has chips=%d bet=%d total=%d", p.ID, p.Chips, p.CurrentBet, p.TotalBetInHand)
+		}
+		if p.CurrentBet > p.TotalBetInHand {
+			return fmt.Errorf("player %s bet %d exceeds their total of %d", p.ID, p.CurrentBet, p.TotalBetInHand)
+		}
+		if p.State == StateAllIn && p.Chips > 0 && g.inHand() {
+			return fmt.Errorf("player %s is all in with %d behind", p.ID, p.Chips)
+		}
+		if g.isBettingRoundLocked() && p.CurrentBet > g.CurrentBet {
+			return fmt.Errorf("player %s bet %d exceeds the current bet of %d", p.ID, p.CurrentBet, g.CurrentBet)
+		}
+	}
+
+	if g.isBettingRoundLocked() && g.RunItOffer == nil {
+		p := g.getPlayerBySeat(g.CurrentPlayerSeat)
+		if p == nil || p.State != StateActive {
+			return fmt.Errorf("seat %d is to act but holds no active player", g.CurrentPlayerSeat)
+		}
+	}
+	return nil
+}
+
+// checkPots allows pots to lag behind the street in progress, since they
+// are only gathered when a street closes, but never to hold chips nobody
+// committed.
+func (g *Game) checkPots() error {
+	committed := g.DeadMoney
+	for _, p := range g.Players {
+		committed += p.TotalBetInHand
+	}
+
+	var total int64
+	for i, pot := range g.Pots {
+		if pot.Amount < 0 {
+			return fmt.Errorf("pot %d holds %d", i, pot.Amount)
+		}
+		if pot.Amount > 0 && len(pot.PlayerIDs) == 0 {
+			return fmt.Errorf("pot %d of %d has nobody eligible", i, pot.Amount)
+		}
+		total += pot.Amount
+
+		seen := make(map[string]bool)
+		for _, id := range pot.PlayerIDs {
+			if seen[id] {
+				return fmt.Errorf("player %s is listed twice in pot %d", id, i)
+			}
+			seen[id] = true
+			p := g.getPlayerByID(id)
+			if p == nil || p.State == StateSittingOut || p.State == StateWaiting {
+				return fmt.Errorf("pot %d names %s, who is not in the hand", i, id)
+			}
+			if i > 0 && !containsID(g.Pots[i-1].PlayerIDs, id) {
+				return fmt.Errorf("side pot %d names %s, who is not in pot %d", i, id, i-1)
+			}
+		}
+	}
+	if total > committed {
+		return fmt.Errorf("pots hold %d but only %d was committed", total, committed)
+	}
+
+	if len(g.Pots) > 0 {
+		for _, p := range g.Players {
+			if isContending(p) && p.TotalBetInHand > 0 && !containsID(g.Pots[0].PlayerIDs, p.ID) {
+				return fmt.Errorf("player %s is still in the hand but not in the main pot", p.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// checkChipTotal follows the hand's log: stacks as the hand started, plus
+// chips added since, must equal what is now in stacks and in the middle.
+func (g *Game) checkChipTotal() error {
+	if len(g.events) == 0 {
+		return nil
+	}
+	started, ok := g.events[0].Data.(HandStarted)
+	if !ok {
+		return nil
+	}
+
+	var expected int64
+	atTable := make(map[string]bool, len(started.Players))
+	for _, s := range started.Players {
+		expected += s.Chips
+		atTable[s.PlayerID] = true
+	}
+	for _, e := range g.events {
+		if d, ok := e.Data.(ChipsAdded); ok && atTable[d.PlayerID] {
+			expected += d.Amount
+		}
+	}
+
+	var actual int64
+	for _, s := range started.Players {
+		p := g.getPlayerByID(s.PlayerID)
+		if p == nil {
+			if g.inHand() {
+				return fmt.Errorf("player %s vanished mid-hand", s.PlayerID)
+			}
+			// Someone left between hands and took their stack along.
+			return nil
+		}
+		actual += p.Chips
+		if g.inHand() {
+			actual += p.TotalBetInHand
+		}
+	}
+	if g.inHand() {
+		actual += g.DeadMoney
+	}
+
+	if actual != expected {
+		return fmt.Errorf("hand %d holds %d chips, expected %d", g.HandNumber, actual, expected)
+	}
+	return nil
+}
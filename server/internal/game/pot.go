@@ -0,0 +1,214 @@
+package game
+
+import (
+	"sort"
+)
+
+type PotResult struct {
+	Index     int              `json:"index"`
+	Amount    int64            `json:"amount"`
+	IsSidePot bool             `json:"isSidePot"`
+	PlayerIDs []string         `json:"playerIds"`
+	Winners   map[string]int64 `json:"winners"`
+}
+
+type HandResult struct {
+	HandNumber int              `json:"handNumber"`
+	HandID     string           `json:"handId"`
+	Winners    map[string]int64 `json:"winners"`
+	Pots       []PotResult      `json:"pots"`
+	Shuffle    *ShuffleRecord   `json:"shuffle,omitempty"`
+	Runs       []RunResult      `json:"runs,omitempty"`
+	Showdown   []ShowdownHand   `json:"showdown,omitempty"` // in the order hands were shown
+}
+
+// calculatePots layers every chip committed this hand into a main pot and
+// side pots. Each distinct TotalBetInHand among players still in the hand
+// closes a layer; only players who covered that layer are eligible for it.
+func (g *Game) calculatePots() []Pot {
+	levels := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, p := range g.Players {
+		if !isContending(p) || p.TotalBetInHand == 0 || seen[p.TotalBetInHand] {
+			continue
+		}
+		seen[p.TotalBetInHand] = true
+		levels = append(levels, p.TotalBetInHand)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	pots := make([]Pot, 0, len(levels))
+	var prev int64
+	for _, level := range levels {
+		pot := Pot{PlayerIDs: make([]string, 0)}
+		for _, p := range g.Players {
+			pot.Amount += min(p.TotalBetInHand, level) - min(p.TotalBetInHand, prev)
+			if isContending(p) && p.TotalBetInHand >= level {
+				pot.PlayerIDs = append(pot.PlayerIDs, p.ID)
+			}
+		}
+		pot.IsSidePot = len(pots) > 0
+		pots = append(pots, pot)
+		prev = level
+	}
+
+	// Chips folded players put in above the highest live contribution can
+	// only be won by whoever contests the top pot. When nobody still in has
+	// a live contribution, as when all in for a dead ante, everyone still in
+	// can win them.
+	var folded int64
+	for _, p := range g.Players {
+		if p.TotalBetInHand > prev {
+			folded += p.TotalBetInHand - prev
+		}
+	}
+	if folded > 0 {
+		if len(pots) > 0 {
+			pots[len(pots)-1].Amount += folded
+		} else {
+			contenders := make([]string, 0)
+			for _, p := range g.Players {
+				if isContending(p) {
+					contenders = append(contenders, p.ID)
+				}
+			}
+			pots = append(pots, Pot{Amount: folded, PlayerIDs: contenders})
+		}
+	}
+
+	return g.addDeadMoney(pots)
+}
+
+// returnUncalledBet gives the biggest bet of the street back down to the
+// most anyone else put in, once betting on it is over. The excess was never
+// called, so it never joins a pot.
+func (g *Game) returnUncalledBet() {
+	var top *Player
+	for _, p := range g.Players {
+		if top == nil || p.CurrentBet > top.CurrentBet {
+			top = p
+		}
+	}
+	if top == nil || !isContending(top) {
+		return
+	}
+
+	var called int64
+	for _, p := range g.Players {
+		if p != top && p.CurrentBet > called {
+			called = p.CurrentBet
+		}
+	}
+	amount := top.CurrentBet - called
+	if amount <= 0 {
+		return
+	}
+
+	refundBet(top, amount)
+	g.CurrentBet = called
+	g.emit(BetReturned{PlayerID: top.ID, SeatIndex: top.SeatIndex, Amount: amount})
+}
+
+func refundBet(p *Player, amount int64) {
+	p.Chips += amount
+	p.CurrentBet -= amount
+	p.TotalBetInHand -= amount
+	if p.State == StateAllIn && p.Chips > 0 {
+		p.State = StateActive
+	}
+}
+
+// addDeadMoney puts forced bets that nobody owns a share of, such as a
+// button ante, into a pot every player still in the hand can win.
+func (g *Game) addDeadMoney(pots []Pot) []Pot {
+	if g.DeadMoney <= 0 {
+		return pots
+	}
+
+	contenders := make([]string, 0)
+	for _, p := range g.Players {
+		if isContending(p) {
+			contenders = append(contenders, p.ID)
+		}
+	}
+
+	if len(pots) > 0 && len(pots[0].PlayerIDs) == len(contenders) {
+		pots[0].Amount += g.DeadMoney
+		return pots
+	}
+
+	pots = append([]Pot{{Amount: g.DeadMoney, PlayerIDs: contenders}}, pots...)
+	for i := range pots {
+		pots[i].IsSidePot = i > 0
+	}
+	return pots
+}
+
+func (g *Game) awardPot(index int, pot Pot, hands map[string]HandRank) PotResult {
+	result := PotResult{
+		Index:     index,
+		Amount:    pot.Amount,
+		IsSidePot: pot.IsSidePot,
+		PlayerIDs: pot.PlayerIDs,
+		Winners:   make(map[string]int64),
+	}
+
+	var best HandRank
+	bestPlayers := make([]*Player, 0)
+	for _, id := range pot.PlayerIDs {
+		p := g.getPlayerByID(id)
+		if p == nil || p.Mucked {
+			continue
+		}
+		hand, ok := hands[id]
+		if !ok {
+			bestPlayers = append(bestPlayers, p)
+			continue
+		}
+		cmp := hand.Compare(best)
+		if len(bestPlayers) == 0 || cmp > 0 {
+			best = hand
+			bestPlayers = []*Player{p}
+		} else if cmp == 0 {
+			bestPlayers = append(bestPlayers, p)
+		}
+	}
+
+	if len(bestPlayers) == 0 {
+		return result
+	}
+
+	g.sortFromButton(bestPlayers)
+	share := pot.Amount / int64(len(bestPlayers))
+	remainder := pot.Amount % int64(len(bestPlayers))
+
+	// Odd chips go to the first winners to the left of the button.
+	for i, p := range bestPlayers {
+		amt := share
+		if int64(i) < remainder {
+			amt++
+		}
+		result.Winners[p.ID] += amt
+	}
+
+	return result
+}
+
+func (g *Game) sortFromButton(players []*Player) {
+	seats := g.Config.MaxPlayers
+	for _, p := range players {
+		if p.SeatIndex >= seats {
+			seats = p.SeatIndex + 1
+		}
+	}
+	distance := func(p *Player) int {
+		return (p.SeatIndex - g.DealerSeat - 1 + seats) % seats
+	}
+	sort.SliceStable(players, func(i, j int) bool {
+		return distance(players[i]) < distance(players[j])
+	})
+}
+
+func isContending(p *Player) bool {
+	return p.State == StateActive || p.State == StateAllIn
+}
@@ -0,0 +1,166 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// RunItOffer is pending while the players in an all-in pot decide how many
+// times to deal the rest of the board. Nobody acts while it is open.
+type RunItOffer struct {
+	PlayerIDs []string       `json:"playerIds"`
+	MaxRuns   int            `json:"maxRuns"`
+	Choices   map[string]int `json:"choices"`
+	Deadline  time.Time      `json:"deadline"`
+}
+
+type RunResult struct {
+	Index        int                          `json:"index"`
+	Board        []Card                       `json:"board"`
+	Pots         []PotResult                  `json:"pots"`
+	Hands        map[string]HandRank          `json:"hands,omitempty"`
+	Descriptions map[string]map[Locale]string `json:"descriptions,omitempty"` // by player, of Hands
+}
+
+// startRunOut deals out the board once nobody is left to act, first asking
+// the players whether to run it more than once when the table allows it.
+// Everyone still in the hand turns their cards up first.
+func (g *Game) startRunOut() {
+	g.showCards()
+
+	maxRuns := g.maxRuns()
+	if maxRuns <= 1 {
+		g.runOutBoard()
+		return
+	}
+
+	contenders := g.getActivePlayers()
+	offer := &RunItOffer{
+		PlayerIDs: make([]string, 0, len(contenders)),
+		MaxRuns:   maxRuns,
+		Choices:   make(map[string]int),
+		Deadline:  time.Now().Add(time.Duration(g.Config.RunItTimeout) * time.Second),
+	}
+	for _, p := range contenders {
+		// Bots never agree to run it more than once.
+		if p.IsBot {
+			g.runOutBoard()
+			return
+		}
+		offer.PlayerIDs = append(offer.PlayerIDs, p.ID)
+	}
+
+	g.RunItOffer = offer
+	g.CurrentPlayerSeat = -1
+	g.ActionDeadline = offer.Deadline
+
+	g.emit(RunItOffered{Offer: *offer})
+}
+
+// maxRuns is how many boards the table allows and the deck can supply.
+func (g *Game) maxRuns() int {
+	need := 5 - len(g.CommunityCards)
+	if need <= 0 || g.Config.MaxRuns <= 1 {
+		return 1
+	}
+	// One burn before each street still to come.
+	switch len(g.CommunityCards) {
+	case 0:
+		need += 3
+	case 3:
+		need += 2
+	default:
+		need++
+	}
+	if fit := g.Deck.RemainingCards() / need; fit < g.Config.MaxRuns {
+		return fit
+	}
+	return g.Config.MaxRuns
+}
+
+// RespondRunIt records how many runs a player agrees to. The board is run
+// the smallest number anyone asked for, so a single "1" settles it.
+func (g *Game) RespondRunIt(playerID string, runs int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	offer := g.RunItOffer
+	if offer == nil {
+		return fmt.Errorf("no run it offer")
+	}
+	eligible := false
+	for _, id := range offer.PlayerIDs {
+		if id == playerID {
+			eligible = true
+			break
+		}
+	}
+	if !eligible {
+		return fmt.Errorf("not in the pot")
+	}
+	if runs < 1 || runs > offer.MaxRuns {
+		return fmt.Errorf("runs must be between 1 and %d", offer.MaxRuns)
+	}
+
+	offer.Choices[playerID] = runs
+	if runs == 1 {
+		g.resolveRunIt(1)
+		return nil
+	}
+	if len(offer.Choices) < len(offer.PlayerIDs) {
+		return nil
+	}
+
+	agreed := offer.MaxRuns
+	for _, choice := range offer.Choices {
+		if choice < agreed {
+			agreed = choice
+		}
+	}
+	g.resolveRunIt(agreed)
+	return nil
+}
+
+// ExpireRunItOffer runs the board once if the offer's deadline has passed
+// without everyone agreeing. Reports whether it did.
+func (g *Game) ExpireRunItOffer(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.RunItOffer == nil || now.Before(g.RunItOffer.Deadline) {
+		return false
+	}
+	g.resolveRunIt(1)
+	return true
+}
+
+func (g *Game) resolveRunIt(runs int) {
+	g.RunItOffer = nil
+	if runs <= 1 {
+		g.runOutBoard()
+		return
+	}
+
+	base := g.CommunityCards
+	g.Boards = make([][]Card, 0, runs)
+	for g.Run = 0; g.Run < runs; g.Run++ {
+		g.CommunityCards = append(make([]Card, 0, 5), base...)
+		g.dealRemainingStreets()
+		g.Boards = append(g.Boards, g.CommunityCards)
+	}
+	g.Run = 0
+	g.CommunityCards = g.Boards[0]
+	g.endHand()
+}
+
+// splitAmount divides a pot between runs; odd chips go to the first run.
+func splitAmount(amount int64, runs int) []int64 {
+	shares := make([]int64, runs)
+	for i := range shares {
+		shares[i] = amount / int64(runs)
+		if int64(i) < amount%int64(runs) {
+			shares[i]++
+		}
+	}
+	return shares
+}
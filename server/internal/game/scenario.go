@@ -0,0 +1,254 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Scenario is a scripted hand: who sits where with what, which cards come
+// out, what everyone does and how the pots should be paid. Cards that are
+// not given are dealt from the rest of the deck in a fixed order.
+type Scenario struct {
+	Name             string           `json:"name"`
+	Variant          string           `json:"variant,omitempty"`
+	BettingStructure string           `json:"bettingStructure,omitempty"`
+	SmallBlind       int64            `json:"smallBlind,omitempty"`
+	BigBlind         int64            `json:"bigBlind,omitempty"`
+	Ante             int64            `json:"ante,omitempty"`
+	Button           int              `json:"button"`          // index into Seats
+	RunIt            int              `json:"runIt,omitempty"` // runs everyone agrees to once all-in
+	Seats            []ScenarioSeat   `json:"seats"`
+	Board            []string         `json:"board,omitempty"`
+	Actions          []ScenarioAction `json:"actions"`
+	Payouts          map[string]int64 `json:"payouts,omitempty"` // expected winnings per player
+	Chips            map[string]int64 `json:"chips,omitempty"`   // expected stacks after the hand
+}
+
+type ScenarioSeat struct {
+	ID    string   `json:"id"`
+	Chips int64    `json:"chips"`
+	Hole  []string `json:"hole,omitempty"`
+}
+
+type ScenarioAction struct {
+	Player string `json:"player"`
+	Action string `json:"action"`
+	Amount int64  `json:"amount,omitempty"`
+	Reject bool   `json:"reject,omitempty"` // the engine must refuse this action
+}
+
+func ParseScenario(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScenario(data)
+}
+
+// Run plays the scenario on a fresh game and checks the expected payouts and
+// stacks. The hand result is returned even when an expectation fails.
+func (s *Scenario) Run() (*HandResult, error) {
+	g, err := s.newGame()
+	if err != nil {
+		return nil, err
+	}
+
+	var result *HandResult
+	g.OnEvent = func(e Event) {
+		if ended, ok := e.Data.(HandEnded); ok {
+			result = &ended.Result
+		}
+	}
+
+	if err := g.StartHand(); err != nil {
+		return nil, fmt.Errorf("%s: start hand: %v", s.Name, err)
+	}
+
+	for i, a := range s.Actions {
+		action, err := ParseActionType(a.Action)
+		if err != nil {
+			return nil, fmt.Errorf("%s: action %d: %v", s.Name, i, err)
+		}
+		err = g.ProcessAction(a.Player, action, a.Amount)
+		if a.Reject && err == nil {
+			return nil, fmt.Errorf("%s: action %d: %s %s %d should be rejected", s.Name, i, a.Player, a.Action, a.Amount)
+		}
+		if !a.Reject && err != nil {
+			return nil, fmt.Errorf("%s: action %d: %s %s %d: %v", s.Name, i, a.Player, a.Action, a.Amount, err)
+		}
+	}
+
+	if offer := g.RunItOffer; offer != nil && s.RunIt > 0 {
+		for _, id := range offer.PlayerIDs {
+			if err := g.RespondRunIt(id, s.RunIt); err != nil {
+				return nil, fmt.Errorf("%s: run it %d: %v", s.Name, s.RunIt, err)
+			}
+		}
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("%s: hand not finished after %d actions, phase %s", s.Name, len(s.Actions), g.Phase)
+	}
+
+	for id, want := range s.Payouts {
+		if got := result.Winners[id]; got != want {
+			return result, fmt.Errorf("%s: payout to %s: expected %d, got %d", s.Name, id, want, got)
+		}
+	}
+	if s.Payouts != nil {
+		for id, got := range result.Winners {
+			if _, ok := s.Payouts[id]; !ok && got != 0 {
+				return result, fmt.Errorf("%s: unexpected payout to %s: %d", s.Name, id, got)
+			}
+		}
+	}
+	for id, want := range s.Chips {
+		p := g.getPlayerByID(id)
+		if p == nil {
+			return result, fmt.Errorf("%s: unknown player %s", s.Name, id)
+		}
+		if p.Chips != want {
+			return result, fmt.Errorf("%s: chips of %s: expected %d, got %d", s.Name, id, want, p.Chips)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Scenario) newGame() (*Game, error) {
+	if len(s.Seats) < 2 {
+		return nil, fmt.Errorf("%s: need at least two seats", s.Name)
+	}
+	if s.Button < 0 || s.Button >= len(s.Seats) {
+		return nil, fmt.Errorf("%s: invalid button %d", s.Name, s.Button)
+	}
+
+	config := DefaultConfig()
+	config.Variant = s.Variant
+	if s.SmallBlind > 0 {
+		config.SmallBlind = s.SmallBlind
+	}
+	if s.BigBlind > 0 {
+		config.BigBlind = s.BigBlind
+	}
+	config.Ante = s.Ante
+	if s.RunIt > 1 {
+		config.MaxRuns = s.RunIt
+	}
+	structure, err := ParseBettingStructure(s.BettingStructure)
+	if err != nil {
+		return nil, err
+	}
+	config.BettingStructure = structure
+	if len(s.Seats) > config.MaxPlayers {
+		config.MaxPlayers = len(s.Seats)
+	}
+
+	variant, err := VariantByName(s.Variant)
+	if err != nil {
+		return nil, err
+	}
+	stack, err := s.stackDeck(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGameWithDeck("scenario", config, stack)
+	for _, seat := range s.Seats {
+		if seat.Chips <= 0 {
+			return nil, fmt.Errorf("%s: seat %s has no chips", s.Name, seat.ID)
+		}
+		if err := g.AddPlayer(NewPlayer(seat.ID, seat.ID, seat.Chips)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The button moves one seat on when the hand starts.
+	if s.Button != 0 {
+		g.DealerSeat = g.Players[s.Button-1].SeatIndex
+	}
+	return g, nil
+}
+
+// stackDeck lays the scripted cards out in dealing order: hole cards one at
+// a time around the table, then burn, flop, burn, turn, burn, river. Gaps
+// are filled from the unused cards.
+func (s *Scenario) stackDeck(variant Variant) (StackedDeck, error) {
+	holeCount := variant.HoleCardCount()
+	n := len(s.Seats)
+	order := make([]*Card, n*holeCount+8)
+
+	deck := variant.NewDeck().Cards()
+	inDeck := make(map[Card]bool, len(deck))
+	for _, c := range deck {
+		inDeck[c] = true
+	}
+
+	used := make(map[Card]bool)
+	place := func(pos int, str string) error {
+		c, err := ParseCard(str)
+		if err != nil {
+			return err
+		}
+		if !inDeck[c] {
+			return fmt.Errorf("%s: card %s not in a %s deck", s.Name, str, variant.Name())
+		}
+		if used[c] {
+			return fmt.Errorf("%s: card %s used twice", s.Name, str)
+		}
+		used[c] = true
+		order[pos] = &c
+		return nil
+	}
+
+	for i, seat := range s.Seats {
+		if len(seat.Hole) > holeCount {
+			return nil, fmt.Errorf("%s: seat %s has more than %d hole cards", s.Name, seat.ID, holeCount)
+		}
+		for round, str := range seat.Hole {
+			if err := place(round*n+i, str); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	boardPos := []int{1, 2, 3, 5, 7}
+	if len(s.Board) > len(boardPos) {
+		return nil, fmt.Errorf("%s: board has more than five cards", s.Name)
+	}
+	for i, str := range s.Board {
+		if err := place(n*holeCount+boardPos[i], str); err != nil {
+			return nil, err
+		}
+	}
+
+	rest := make([]Card, 0, len(deck))
+	for _, c := range deck {
+		if !used[c] {
+			rest = append(rest, c)
+		}
+	}
+	if len(rest) < len(order)-len(used) {
+		return nil, fmt.Errorf("%s: not enough cards in the deck", s.Name)
+	}
+
+	stack := make(StackedDeck, len(order))
+	for i, c := range order {
+		if c != nil {
+			stack[i] = *c
+			continue
+		}
+		stack[i] = rest[0]
+		rest = rest[1:]
+	}
+	return stack, nil
+}
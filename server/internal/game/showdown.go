@@ -0,0 +1,173 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ShowdownHand is one player's turn at showdown. Mucked hands keep their
+// cards private and give up any claim to the pot.
+type ShowdownHand struct {
+	PlayerID    string            `json:"playerId"`
+	SeatIndex   int               `json:"seatIndex"`
+	Mucked      bool              `json:"mucked"`
+	Cards       []Card            `json:"cards,omitempty"`
+	Hand        *HandRank         `json:"hand,omitempty"` // best five cards on the first board
+	Description map[Locale]string `json:"description,omitempty"`
+}
+
+// showdown reveals hands in turn. The last aggressor on the final street
+// shows first, or the first player left of the button when nobody bet.
+// Everyone after them shows only a hand that can still win a pot they are
+// in, unless they asked to always show.
+func (g *Game) showdown(hands map[string]HandRank) []ShowdownHand {
+	order := g.showdownOrder()
+	shown := make([]*Player, 0, len(order))
+	result := make([]ShowdownHand, 0, len(order))
+
+	for _, p := range order {
+		hand, ok := hands[p.ID]
+		faceUp := len(p.ShownCards) == len(p.HoleCards)
+		if ok && !faceUp && !p.AlwaysShow && !g.canStillWin(p, hand, shown, hands) {
+			p.Mucked = true
+			g.emit(CardsMucked{PlayerID: p.ID, SeatIndex: p.SeatIndex})
+			result = append(result, ShowdownHand{PlayerID: p.ID, SeatIndex: p.SeatIndex, Mucked: true})
+			continue
+		}
+
+		g.revealCards(p, p.HoleCards)
+		shown = append(shown, p)
+		entry := ShowdownHand{PlayerID: p.ID, SeatIndex: p.SeatIndex, Cards: p.HoleCards}
+		if ok {
+			entry.Hand = &hand
+			entry.Description = hand.Descriptions()
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func (g *Game) showdownOrder() []*Player {
+	order := g.getActivePlayers()
+	start := g.DealerSeat
+	if p := g.getPlayerBySeat(g.LastAggressorSeat); p != nil && isContending(p) {
+		start = p.SeatIndex - 1
+	}
+
+	seats := g.seatCount()
+	distance := func(p *Player) int {
+		return (p.SeatIndex - start - 1 + 2*seats) % seats
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distance(order[i]) < distance(order[j])
+	})
+	return order
+}
+
+// canStillWin reports whether hand would win or tie at least one pot p is
+// eligible for against the hands already shown.
+func (g *Game) canStillWin(p *Player, hand HandRank, shown []*Player, hands map[string]HandRank) bool {
+	for _, pot := range g.Pots {
+		if !containsID(pot.PlayerIDs, p.ID) {
+			continue
+		}
+		beaten := false
+		for _, other := range shown {
+			if containsID(pot.PlayerIDs, other.ID) && hands[other.ID].Compare(hand) > 0 {
+				beaten = true
+				break
+			}
+		}
+		if !beaten {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []string, id string) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// showCards turns the hole cards of everyone still in the hand face up, as
+// when the pot is all in.
+func (g *Game) showCards() {
+	for _, p := range g.getActivePlayers() {
+		g.revealCards(p, p.HoleCards)
+	}
+}
+
+// revealCards turns cards face up, skipping any already shown.
+func (g *Game) revealCards(p *Player, cards []Card) {
+	revealed := make([]Card, 0, len(cards))
+	for _, c := range cards {
+		if !containsCard(p.ShownCards, c) {
+			revealed = append(revealed, c)
+		}
+	}
+	if len(revealed) == 0 {
+		return
+	}
+	p.ShownCards = append(p.ShownCards, revealed...)
+	g.emit(CardsShown{PlayerID: p.ID, SeatIndex: p.SeatIndex, Cards: revealed})
+}
+
+func containsCard(cards []Card, c Card) bool {
+	for _, x := range cards {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowCards lets the winner of a pot nobody contested show some or all of
+// their hole cards once the hand is over. Indices pick the cards.
+func (g *Game) ShowCards(playerID string, indices []int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	if g.Phase != PhaseFinished {
+		return fmt.Errorf("hand is not over")
+	}
+	contenders := g.getActivePlayers()
+	if len(contenders) != 1 || contenders[0] != player {
+		return fmt.Errorf("only an uncontested winner may show")
+	}
+	if len(indices) == 0 {
+		return fmt.Errorf("no cards chosen")
+	}
+
+	cards := make([]Card, 0, len(indices))
+	for _, i := range indices {
+		if i < 0 || i >= len(player.HoleCards) {
+			return fmt.Errorf("invalid card index %d", i)
+		}
+		cards = append(cards, player.HoleCards[i])
+	}
+	g.revealCards(player, cards)
+	return nil
+}
+
+// SetAlwaysShow has a player show losing hands at showdown rather than
+// muck them.
+func (g *Game) SetAlwaysShow(playerID string, enabled bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player := g.getPlayerByID(playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	player.AlwaysShow = enabled
+	return nil
+}
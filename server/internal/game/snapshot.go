@@ -0,0 +1,120 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SnapshotVersion is bumped whenever the snapshot layout changes; older
+// snapshots are refused rather than half restored.
+const SnapshotVersion = 1
+
+// Snapshot is everything needed to carry on a game after a restart, hand in
+// progress included. It holds the deck order and the unrevealed server seeds,
+// so it must be kept as private as the seed itself.
+type Snapshot struct {
+	Version    int              `json:"version"`
+	Game       json.RawMessage  `json:"game"`
+	Deck       []Card           `json:"deck"`
+	DeckIndex  int              `json:"deckIndex"`
+	ServerSeed string           `json:"serverSeed"`
+	NextSeed   string           `json:"nextSeed,omitempty"`
+	EventSeq   int64            `json:"eventSeq"`
+	Events     []Event          `json:"events"`
+	Players    []PlayerSnapshot `json:"players"`
+}
+
+// PlayerSnapshot holds the player state the game JSON leaves out.
+type PlayerSnapshot struct {
+	ID             string `json:"id"`
+	ClientSeed     string `json:"clientSeed,omitempty"`
+	SitOutNextHand bool   `json:"sitOutNextHand,omitempty"`
+}
+
+func (g *Game) Snapshot() (*Snapshot, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	state, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		Version:    SnapshotVersion,
+		Game:       state,
+		Deck:       append([]Card(nil), g.Deck.cards...),
+		DeckIndex:  g.Deck.index,
+		ServerSeed: g.serverSeed,
+		NextSeed:   g.nextSeed,
+		EventSeq:   g.eventSeq,
+		Events:     append([]Event(nil), g.events...),
+		Players:    make([]PlayerSnapshot, 0, len(g.Players)),
+	}
+	for _, p := range g.Players {
+		s.Players = append(s.Players, PlayerSnapshot{
+			ID:             p.ID,
+			ClientSeed:     p.ClientSeed,
+			SitOutNextHand: p.sitOutNextHand,
+		})
+	}
+	return s, nil
+}
+
+// RestoreGame rebuilds a game from a snapshot, ready to continue where it
+// stopped. Callbacks are not part of a snapshot and have to be set again.
+func RestoreGame(s *Snapshot) (*Game, error) {
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+
+	g := &Game{}
+	if err := json.Unmarshal(s.Game, g); err != nil {
+		return nil, fmt.Errorf("invalid game state: %v", err)
+	}
+
+	variant, err := VariantByName(g.Config.Variant)
+	if err != nil {
+		return nil, err
+	}
+	deck := variant.NewDeck()
+	if len(s.Deck) != len(deck.full) || s.DeckIndex < 0 || s.DeckIndex > len(s.Deck) {
+		return nil, fmt.Errorf("deck does not match %s", variant.Name())
+	}
+	inDeck := make(map[Card]bool, len(s.Deck))
+	for _, c := range s.Deck {
+		inDeck[c] = true
+	}
+	for _, c := range deck.full {
+		if !inDeck[c] {
+			return nil, fmt.Errorf("deck does not match %s", variant.Name())
+		}
+	}
+	deck.cards = append([]Card(nil), s.Deck...)
+	deck.index = s.DeckIndex
+
+	g.Deck = deck
+	g.variant = variant
+	g.deckSource = FairShuffle{}
+	g.serverSeed = s.ServerSeed
+	g.nextSeed = s.NextSeed
+	if g.nextSeed == "" || SeedHash(g.nextSeed) != g.NextSeedHash {
+		g.commitNextSeed()
+	}
+	g.eventSeq = s.EventSeq
+	g.events = s.Events
+	if g.Pots == nil {
+		g.Pots = make([]Pot, 0)
+	}
+	if g.CommunityCards == nil {
+		g.CommunityCards = make([]Card, 0, 5)
+	}
+
+	for _, ps := range s.Players {
+		if p := g.getPlayerByID(ps.ID); p != nil {
+			p.ClientSeed = ps.ClientSeed
+			p.sitOutNextHand = ps.SitOutNextHand
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"fmt"
+)
+
+// Variant describes what changes between poker games played on the same
+// betting engine: the deck, how many hole cards are dealt, the forced bets
+// and how a hand is scored.
+type Variant interface {
+	Name() string
+	HoleCardCount() int
+	NewDeck() *Deck
+	ForcedBets() ForcedBets
+	// DiscardAfter is the street whose betting is followed by everyone
+	// throwing away one hole card, or PhaseWaiting if nobody discards.
+	DiscardAfter() Phase
+	EvaluateHand(holeCards, communityCards []Card) HandRank
+}
+
+type ForcedBets int
+
+const (
+	// ForcedBetsBlinds posts a small and big blind, plus Config.Ante from
+	// every player when set.
+	ForcedBetsBlinds ForcedBets = iota
+	// ForcedBetsButtonAnte posts no blinds; the button alone puts in a dead
+	// ante for the whole table.
+	ForcedBetsButtonAnte
+)
+
+var (
+	Holdem         Variant = holdem{}
+	Omaha          Variant = omaha{holeCards: 4}
+	ShortDeck      Variant = shortDeck{}
+	Pineapple      Variant = pineapple{name: "pineapple", discardAfter: PhasePreflop}
+	CrazyPineapple Variant = pineapple{name: "crazy_pineapple", discardAfter: PhaseFlop}
+)
+
+var variants = map[string]Variant{
+	Holdem.Name():         Holdem,
+	Omaha.Name():          Omaha,
+	ShortDeck.Name():      ShortDeck,
+	Pineapple.Name():      Pineapple,
+	CrazyPineapple.Name(): CrazyPineapple,
+}
+
+func VariantByName(name string) (Variant, error) {
+	if name == "" {
+		return Holdem, nil
+	}
+	v, ok := variants[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variant: %s", name)
+	}
+	return v, nil
+}
+
+type holdem struct{}
+
+func (holdem) Name() string           { return "holdem" }
+func (holdem) HoleCardCount() int     { return 2 }
+func (holdem) NewDeck() *Deck         { return NewDeck() }
+func (holdem) ForcedBets() ForcedBets { return ForcedBetsBlinds }
+func (holdem) DiscardAfter() Phase    { return PhaseWaiting }
+
+func (holdem) EvaluateHand(holeCards, communityCards []Card) HandRank {
+	return EvaluateHand(holeCards, communityCards)
+}
+
+type omaha struct {
+	holeCards int
+}
+
+func (o omaha) Name() string           { return "omaha" }
+func (o omaha) HoleCardCount() int     { return o.holeCards }
+func (o omaha) NewDeck() *Deck         { return NewDeck() }
+func (o omaha) ForcedBets() ForcedBets { return ForcedBetsBlinds }
+func (o omaha) DiscardAfter() Phase    { return PhaseWaiting }
+
+func (o omaha) EvaluateHand(holeCards, communityCards []Card) HandRank {
+	return EvaluateOmahaHand(holeCards, communityCards)
+}
+
+type shortDeck struct{}
+
+func (shortDeck) Name() string           { return "short_deck" }
+func (shortDeck) HoleCardCount() int     { return 2 }
+func (shortDeck) NewDeck() *Deck         { return NewShortDeck() }
+func (shortDeck) ForcedBets() ForcedBets { return ForcedBetsButtonAnte }
+func (shortDeck) DiscardAfter() Phase    { return PhaseWaiting }
+
+func (shortDeck) EvaluateHand(holeCards, communityCards []Card) HandRank {
+	return EvaluateShortDeckHand(holeCards, communityCards)
+}
+
+// pineapple deals three hole cards and has everyone discard one, after the
+// preflop betting or, in Crazy Pineapple, after the flop's. What is left
+// plays as hold'em.
+type pineapple struct {
+	name         string
+	discardAfter Phase
+}
+
+func (p pineapple) Name() string           { return p.name }
+func (p pineapple) HoleCardCount() int     { return 3 }
+func (p pineapple) NewDeck() *Deck         { return NewDeck() }
+func (p pineapple) ForcedBets() ForcedBets { return ForcedBetsBlinds }
+func (p pineapple) DiscardAfter() Phase    { return p.discardAfter }
+
+func (p pineapple) EvaluateHand(holeCards, communityCards []Card) HandRank {
+	return EvaluateHand(holeCards, communityCards)
+}
+
+// EvaluateOmahaHand scores the best hand made from exactly two hole cards
+// and exactly three community cards.
+func EvaluateOmahaHand(holeCards, communityCards []Card) HandRank {
+	if len(holeCards) < 2 || len(communityCards) < 3 {
+		return HandRank{Type: HighCard}
+	}
+
+	var bestHand HandRank
+	found := false
+	for _, hole := range getCombinations(holeCards, 2) {
+		for _, board := range getCombinations(communityCards, 3) {
+			combo := append(append(make([]Card, 0, 5), hole...), board...)
+			hand := evaluateFiveCards(combo, false)
+			if !found || hand.Compare(bestHand) > 0 {
+				bestHand = hand
+				found = true
+			}
+		}
+	}
+
+	return bestHand
+}
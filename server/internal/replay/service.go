@@ -48,10 +48,11 @@ type HandHistory struct {
 }
 
 type WinnerInfo struct {
-	PlayerID string        `json:"playerId"`
-	Amount   int64         `json:"amount"`
-	HandType string        `json:"handType"`
-	Cards    []game.Card   `json:"cards"`
+	PlayerID     string                 `json:"playerId"`
+	Amount       int64                  `json:"amount"`
+	HandType     string                 `json:"handType"` // full English description
+	Descriptions map[game.Locale]string `json:"descriptions,omitempty"`
+	Cards        []game.Card            `json:"cards"`
 }
 
 type Service struct {
@@ -155,7 +156,9 @@ func (s *Service) RecordAction(handID string, playerID, playerName string, actio
 	lastPhase.Actions = append(lastPhase.Actions, record)
 }
 
-func (s *Service) FinishRecording(handID string, communityCards []game.Card, winners map[string]int64, handTypes map[string]string, winningCards map[string][]game.Card, totalPot int64) {
+// FinishRecording closes a hand's history. hands holds the shown hands;
+// winners of an uncontested pot have none.
+func (s *Service) FinishRecording(handID string, communityCards []game.Card, winners map[string]int64, hands map[string]game.HandRank, totalPot int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -174,11 +177,12 @@ func (s *Service) FinishRecording(handID string, communityCards []game.Card, win
 		info := WinnerInfo{
 			PlayerID: playerID,
 			Amount:   amount,
-			HandType: handTypes[playerID],
 		}
-		if cards, ok := winningCards[playerID]; ok {
-			info.Cards = make([]game.Card, len(cards))
-			copy(info.Cards, cards)
+		if hand, ok := hands[playerID]; ok {
+			info.HandType = hand.Describe(game.LocaleEnglish)
+			info.Descriptions = hand.Descriptions()
+			info.Cards = make([]game.Card, len(hand.Cards))
+			copy(info.Cards, hand.Cards)
 		}
 		history.Winners = append(history.Winners, info)
 	}
@@ -0,0 +1,103 @@
+package room
+
+import (
+	"time"
+)
+
+const clockInterval = time.Second
+
+type roomEvent struct {
+	eventType string
+	data      interface{}
+}
+
+func (r *Room) runClock() {
+	ticker := time.NewTicker(clockInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopClock:
+			return
+		case now := <-ticker.C:
+			r.tickClock(now)
+		}
+	}
+}
+
+func (r *Room) tickClock(now time.Time) {
+	events := r.advanceClock(now)
+	for _, ev := range events {
+		r.emit(ev.eventType, ev.data)
+	}
+}
+
+// advanceClock runs under the room lock and only collects events, so
+// handlers are free to read room state back when they are emitted.
+func (r *Room) advanceClock(now time.Time) []roomEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Game.ExpireDiscards(now) {
+		return []roomEvent{{"game_state", nil}}
+	}
+
+	if !r.Game.IsBettingRound() {
+		return nil
+	}
+
+	if r.Game.ExpireRunItOffer(now) {
+		return []roomEvent{{"game_state", nil}}
+	}
+
+	if result := r.Game.ExpireAction(now); result != nil {
+		if result.TimeBankUsed > 0 {
+			return []roomEvent{{"time_bank_used", result}}
+		}
+		return []roomEvent{
+			{"action_timeout", map[string]interface{}{
+				"playerId":  result.PlayerID,
+				"seatIndex": result.SeatIndex,
+				"action":    result.Action.String(),
+			}},
+			{"game_state", nil},
+		}
+	}
+
+	player := r.Game.GetCurrentPlayer()
+	if player == nil {
+		return nil
+	}
+
+	remaining := r.Game.ActionDeadline.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return []roomEvent{{"action_timer", map[string]interface{}{
+		"playerId":  player.ID,
+		"seatIndex": player.SeatIndex,
+		"remaining": int(remaining.Round(time.Second) / time.Second),
+		"deadline":  r.Game.ActionDeadline.UnixMilli(),
+		"timeBank":  player.TimeBank,
+	}}}
+}
+
+func (r *Room) UseTimeBank(playerID string) error {
+	r.mu.Lock()
+	result, err := r.Game.UseTimeBank(playerID)
+	r.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	r.emit("time_bank_used", result)
+	return nil
+}
+
+func (r *Room) emit(eventType string, data interface{}) {
+	if r.onGameEvent != nil {
+		r.onGameEvent(eventType, data)
+	}
+}
@@ -0,0 +1,82 @@
+package room
+
+import (
+	"texas-holdem-server/internal/game"
+	"texas-holdem-server/internal/replay"
+)
+
+func (r *Room) SetHandRecorder(replays *replay.Service) {
+	r.replays = replays
+}
+
+// recordHand keeps the hand history for replays as the game's events come
+// in. The history starts once the hole cards are dealt, so it holds them,
+// with everyone's stack as it was before the blinds.
+func (r *Room) recordHand(e game.Event) {
+	if r.replays == nil {
+		return
+	}
+
+	switch d := e.Data.(type) {
+	case game.HandStarted:
+		r.historyID = ""
+	case game.StreetDealt:
+		if d.Phase == game.PhasePreflop {
+			dealtIn := make([]*game.Player, 0, len(r.Game.Players))
+			for _, p := range r.Game.Players {
+				if len(p.HoleCards) > 0 {
+					dealtIn = append(dealtIn, &game.Player{
+						ID:        p.ID,
+						Name:      p.Name,
+						SeatIndex: p.SeatIndex,
+						Chips:     p.Chips + p.TotalBetInHand,
+						HoleCards: p.HoleCards,
+						IsDealer:  p.IsDealer,
+					})
+				}
+			}
+			config := r.Game.Config
+			r.historyID = r.replays.StartRecording(r.ID, e.HandNumber, dealtIn, config.SmallBlind, config.BigBlind).ID
+		}
+		if r.historyID != "" {
+			r.replays.RecordPhase(r.historyID, d.Phase, d.Board, r.chipsInPot())
+		}
+	case game.ActionTaken:
+		if r.historyID == "" {
+			return
+		}
+		// The game lock is held, so its players are read directly.
+		var name string
+		for _, p := range r.Game.Players {
+			if p.ID == d.PlayerID {
+				name = p.Name
+			}
+		}
+		r.replays.RecordAction(r.historyID, d.PlayerID, name, d.Action, d.Amount)
+	case game.HandEnded:
+		if r.historyID == "" {
+			return
+		}
+		hands := make(map[string]game.HandRank)
+		for _, shown := range d.Result.Showdown {
+			if shown.Hand != nil && !shown.Mucked {
+				hands[shown.PlayerID] = *shown.Hand
+			}
+		}
+		var pot int64
+		for _, p := range d.Result.Pots {
+			pot += p.Amount
+		}
+		r.replays.FinishRecording(r.historyID, r.Game.CommunityCards, d.Result.Winners, hands, pot)
+		r.historyID = ""
+	}
+}
+
+// chipsInPot is everything bet this hand, in the pots or not yet.
+func (r *Room) chipsInPot() int64 {
+	total := r.Game.DeadMoney
+	for _, p := range r.Game.Players {
+		total += p.TotalBetInHand
+	}
+	return total
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"texas-holdem-server/internal/game"
+	"texas-holdem-server/internal/replay"
 )
 
 type MatchRequest struct {
@@ -17,16 +18,19 @@ type MatchRequest struct {
 }
 
 type Manager struct {
-	rooms        map[string]*Room
-	matchQueue   []MatchRequest
-	mu           sync.RWMutex
-	onRoomEvent  func(roomID, eventType string, data interface{})
+	rooms       map[string]*Room
+	matchQueue  []MatchRequest
+	mu          sync.RWMutex
+	shuffles    *shuffleLog
+	replays     *replay.Service
+	onRoomEvent func(roomID, eventType string, data interface{})
 }
 
 func NewManager(hub interface{}) *Manager {
 	m := &Manager{
 		rooms:      make(map[string]*Room),
 		matchQueue: make([]MatchRequest, 0),
+		shuffles:   newShuffleLog(maxShuffleRecords),
 	}
 
 	go m.cleanupRoutine()
@@ -39,11 +43,27 @@ func (m *Manager) SetEventHandler(handler func(roomID, eventType string, data in
 	m.onRoomEvent = handler
 }
 
+// SetHandRecorder has every room from now on keep its hand histories in
+// replays.
+func (m *Manager) SetHandRecorder(replays *replay.Service) {
+	m.replays = replays
+}
+
 func (m *Manager) CreateRoom(config RoomConfig) (*Room, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	room := NewRoom(config)
+	m.addRoomLocked(room)
+
+	return room, nil
+}
+
+func (m *Manager) addRoomLocked(room *Room) {
 	m.rooms[room.ID] = room
 
 	room.SetEventHandler(func(eventType string, data interface{}) {
@@ -51,8 +71,8 @@ func (m *Manager) CreateRoom(config RoomConfig) (*Room, error) {
 			m.onRoomEvent(room.ID, eventType, data)
 		}
 	})
-
-	return room, nil
+	room.SetShuffleRecorder(m.shuffles.add)
+	room.SetHandRecorder(m.replays)
 }
 
 func (m *Manager) GetRoom(roomID string) *Room {
@@ -64,7 +84,10 @@ func (m *Manager) GetRoom(roomID string) *Room {
 func (m *Manager) DeleteRoom(roomID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.rooms, roomID)
+	if room, ok := m.rooms[roomID]; ok {
+		room.Close()
+		delete(m.rooms, roomID)
+	}
 }
 
 func (m *Manager) JoinRoom(roomID, playerID, name string, chips int64) error {
@@ -75,6 +98,13 @@ func (m *Manager) JoinRoom(roomID, playerID, name string, chips int64) error {
 	if !exists {
 		return fmt.Errorf("room not found")
 	}
+	// Players still seated in a restored room just reconnect.
+	if room.HasPlayer(playerID) {
+		return nil
+	}
+	if room.IsTournament() {
+		return fmt.Errorf("tournament seats are assigned by the tournament")
+	}
 
 	return room.AddPlayer(playerID, name, chips)
 }
@@ -87,6 +117,11 @@ func (m *Manager) LeaveRoom(roomID, playerID string) error {
 	if !exists {
 		return fmt.Errorf("room not found")
 	}
+	// A tournament player who walks away keeps their seat and is blinded
+	// off until they bust.
+	if room.IsTournament() {
+		return nil
+	}
 
 	err := room.RemovePlayer(playerID)
 
@@ -121,10 +156,73 @@ func (m *Manager) SitIn(roomID, playerID string) {
 	}
 }
 
-func (m *Manager) BuyIn(roomID, playerID string, amount int64) {
-	if room := m.GetRoom(roomID); room != nil {
-		room.BuyIn(playerID, amount)
+func (m *Manager) BuyIn(roomID, playerID string, amount int64) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
 	}
+	return room.BuyIn(playerID, amount)
+}
+
+func (m *Manager) UseTimeBank(roomID, playerID string) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.UseTimeBank(playerID)
+}
+
+func (m *Manager) SetStraddle(roomID, playerID string, enabled bool) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.SetStraddle(playerID, enabled)
+}
+
+func (m *Manager) ShowCards(roomID, playerID string, indices []int) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.ShowCards(playerID, indices)
+}
+
+func (m *Manager) SetAlwaysShow(roomID, playerID string, enabled bool) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.SetAlwaysShow(playerID, enabled)
+}
+
+func (m *Manager) Discard(roomID, playerID string, index int) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.Discard(playerID, index)
+}
+
+func (m *Manager) RespondRunIt(roomID, playerID string, runs int) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.RespondRunIt(playerID, runs)
+}
+
+func (m *Manager) SetClientSeed(roomID, playerID, seed string) error {
+	room := m.GetRoom(roomID)
+	if room == nil {
+		return fmt.Errorf("room not found")
+	}
+	return room.SetClientSeed(playerID, seed)
+}
+
+// GetShuffleRecord returns the revealed shuffle of a finished hand.
+func (m *Manager) GetShuffleRecord(handID string) (game.ShuffleRecord, bool) {
+	return m.shuffles.get(handID)
 }
 
 func (m *Manager) QuickMatch(playerID, name string, blindLevel int) (string, error) {
@@ -156,15 +254,10 @@ func (m *Manager) QuickMatch(playerID, name string, blindLevel int) (string, err
 	}
 
 	room := NewRoom(config)
-	m.rooms[room.ID] = room
-
-	room.SetEventHandler(func(eventType string, data interface{}) {
-		if m.onRoomEvent != nil {
-			m.onRoomEvent(room.ID, eventType, data)
-		}
-	})
+	m.addRoomLocked(room)
 
 	if err := room.AddPlayer(playerID, name, 1000); err != nil {
+		room.Close()
 		delete(m.rooms, room.ID)
 		return "", err
 	}
@@ -234,7 +327,7 @@ func (m *Manager) fillWithAI(roomID string, count int) {
 	}
 
 	if room.Game.CanStartHand() {
-		room.Game.StartHand()
+		room.startHandAfter(0)
 	}
 }
 
@@ -267,6 +360,7 @@ func (m *Manager) cleanupRoutine() {
 		m.mu.Lock()
 		for id, room := range m.rooms {
 			if room.IsEmpty() && time.Since(room.CreatedAt) > 10*time.Minute {
+				room.Close()
 				delete(m.rooms, id)
 			}
 		}
@@ -312,7 +406,7 @@ func (m *Manager) processMatchQueue() {
 		}
 
 		room := NewRoom(config)
-		m.rooms[room.ID] = room
+		m.addRoomLocked(room)
 
 		maxPlayers := min(len(requests), config.MaxPlayers)
 		matched := requests[:maxPlayers]
@@ -8,38 +8,88 @@ import (
 
 	"github.com/google/uuid"
 	"texas-holdem-server/internal/game"
+	"texas-holdem-server/internal/replay"
 )
 
 type RoomConfig struct {
-	SmallBlind int64  `json:"smallBlind"`
-	BigBlind   int64  `json:"bigBlind"`
-	MaxPlayers int    `json:"maxPlayers"`
-	MinPlayers int    `json:"minPlayers"`
-	IsPrivate  bool   `json:"isPrivate"`
-	Password   string `json:"password,omitempty"`
-	AutoStart  bool   `json:"autoStart"`
+	SmallBlind    int64  `json:"smallBlind"`
+	BigBlind      int64  `json:"bigBlind"`
+	MaxPlayers    int    `json:"maxPlayers"`
+	MinPlayers    int    `json:"minPlayers"`
+	IsPrivate     bool   `json:"isPrivate"`
+	Password      string `json:"password,omitempty"`
+	AutoStart     bool   `json:"autoStart"`
+	ActionTimeout int    `json:"actionTimeout"` // seconds
+	TimeBank      int    `json:"timeBank"`      // seconds
+
+	Variant          string `json:"variant,omitempty"`          // holdem, omaha, short_deck, pineapple, crazy_pineapple
+	BettingStructure string `json:"bettingStructure,omitempty"` // no_limit, pot_limit, fixed_limit
+	RaiseCap         int    `json:"raiseCap,omitempty"`
+	MaxRuns          int    `json:"maxRuns,omitempty"` // run it twice/three times when all-in
+	Ante             int64  `json:"ante,omitempty"`
+	AnteMode         string `json:"anteMode,omitempty"` // per_player, big_blind, button
+	Straddle         string `json:"straddle,omitempty"` // none, utg, button
+
+	// TournamentID marks a tournament table: seats are assigned by the
+	// tournament, stacks are never topped up and nobody leaves until they
+	// bust.
+	TournamentID string `json:"tournamentId,omitempty"`
+}
+
+func (c RoomConfig) Validate() error {
+	variant, err := game.VariantByName(c.Variant)
+	if err != nil {
+		return err
+	}
+	if _, err := game.ParseBettingStructure(c.BettingStructure); err != nil {
+		return err
+	}
+	// Burns plus a full board take eight cards.
+	if c.MaxPlayers*variant.HoleCardCount()+8 > variant.NewDeck().RemainingCards() {
+		return fmt.Errorf("too many seats for %s", variant.Name())
+	}
+	if c.RaiseCap < 0 {
+		return fmt.Errorf("invalid raise cap")
+	}
+	if c.MaxRuns < 0 || c.MaxRuns > 3 {
+		return fmt.Errorf("invalid max runs")
+	}
+	if c.Ante < 0 {
+		return fmt.Errorf("invalid ante")
+	}
+	if _, err := game.ParseAnteMode(c.AnteMode); err != nil {
+		return err
+	}
+	if _, err := game.ParseStraddleMode(c.Straddle); err != nil {
+		return err
+	}
+	return nil
 }
 
 func DefaultRoomConfig() RoomConfig {
 	return RoomConfig{
-		SmallBlind: 10,
-		BigBlind:   20,
-		MaxPlayers: 9,
-		MinPlayers: 2,
-		IsPrivate:  false,
-		AutoStart:  true,
+		SmallBlind:    10,
+		BigBlind:      20,
+		MaxPlayers:    9,
+		MinPlayers:    2,
+		IsPrivate:     false,
+		AutoStart:     true,
+		ActionTimeout: 30,
+		TimeBank:      60,
 	}
 }
 
 type RoomInfo struct {
-	ID             string       `json:"id"`
-	Name           string       `json:"name"`
-	SmallBlind     int64        `json:"smallBlind"`
-	BigBlind       int64        `json:"bigBlind"`
-	MaxPlayers     int          `json:"maxPlayers"`
-	CurrentPlayers int          `json:"currentPlayers"`
-	IsPrivate      bool         `json:"isPrivate"`
-	Players        []PlayerInfo `json:"players"`
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	SmallBlind       int64        `json:"smallBlind"`
+	BigBlind         int64        `json:"bigBlind"`
+	Variant          string       `json:"variant"`
+	BettingStructure string       `json:"bettingStructure"`
+	MaxPlayers       int          `json:"maxPlayers"`
+	CurrentPlayers   int          `json:"currentPlayers"`
+	IsPrivate        bool         `json:"isPrivate"`
+	Players          []PlayerInfo `json:"players"`
 }
 
 type PlayerInfo struct {
@@ -57,20 +107,57 @@ type Room struct {
 	Game      *game.Game
 	CreatedAt time.Time
 	mu        sync.RWMutex
+	stopClock chan struct{}
+	closeOnce sync.Once
+
+	onGameEvent       func(eventType string, data interface{})
+	onShuffleRevealed func(record game.ShuffleRecord)
+	onHandEnded       func(r *Room, result game.HandResult, stacks map[string]int64)
+	replays           *replay.Service
+	historyID         string // the hand being recorded for replays
+	nextStakes        *stakes
+	bounties          map[string]int64 // by player, at a knockout tournament table
+
+	// observing is set while the hand observer runs and held until
+	// Release; neither lets a new hand be dealt. holdAfterHand sets held
+	// again once the next hand is.
+	observing     bool
+	held          bool
+	holdAfterHand bool
+}
 
-	onGameEvent func(eventType string, data interface{})
+type stakes struct {
+	smallBlind, bigBlind, ante int64
 }
 
 func NewRoom(config RoomConfig) *Room {
 	id := uuid.New().String()[:8]
-	
-	gameConfig := game.GameConfig{
-		SmallBlind:    config.SmallBlind,
-		BigBlind:      config.BigBlind,
-		MaxPlayers:    config.MaxPlayers,
-		MinPlayers:    config.MinPlayers,
-		ActionTimeout: 30,
+
+	gameConfig := game.DefaultConfig()
+	gameConfig.SmallBlind = config.SmallBlind
+	gameConfig.BigBlind = config.BigBlind
+	gameConfig.MaxPlayers = config.MaxPlayers
+	gameConfig.MinPlayers = config.MinPlayers
+	if config.ActionTimeout > 0 {
+		gameConfig.ActionTimeout = config.ActionTimeout
+	}
+	if config.TimeBank > 0 {
+		gameConfig.TimeBank = config.TimeBank
 	}
+	gameConfig.Variant = config.Variant
+	gameConfig.BettingStructure, _ = game.ParseBettingStructure(config.BettingStructure)
+	if config.Variant == game.Omaha.Name() && config.BettingStructure == "" {
+		gameConfig.BettingStructure = game.PotLimit
+	}
+	if config.RaiseCap > 0 {
+		gameConfig.RaiseCap = config.RaiseCap
+	}
+	if config.MaxRuns > 0 {
+		gameConfig.MaxRuns = config.MaxRuns
+	}
+	gameConfig.Ante = config.Ante
+	gameConfig.AnteMode, _ = game.ParseAnteMode(config.AnteMode)
+	gameConfig.Straddle, _ = game.ParseStraddleMode(config.Straddle)
 
 	r := &Room{
 		ID:        id,
@@ -78,60 +165,170 @@ func NewRoom(config RoomConfig) *Room {
 		Config:    config,
 		Game:      game.NewGame(id, gameConfig),
 		CreatedAt: time.Now(),
+		stopClock: make(chan struct{}),
 	}
 
 	r.setupGameCallbacks()
+	go r.runClock()
 	return r
 }
 
+func (r *Room) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stopClock)
+	})
+}
+
+// setupGameCallbacks turns the game's event log into room messages. Hole
+// cards are left out; players see their own in the game state.
 func (r *Room) setupGameCallbacks() {
-	r.Game.OnPhaseChange = func(phase game.Phase) {
-		if r.onGameEvent != nil {
-			r.onGameEvent("phase_change", map[string]interface{}{
-				"phase": phase.String(),
+	r.Game.OnEvent = func(e game.Event) {
+		r.recordHand(e)
+		switch d := e.Data.(type) {
+		case game.HandStarted:
+			r.emit("shuffle_commit", map[string]interface{}{
+				"handId":      d.Shuffle.HandID,
+				"seedHash":    d.Shuffle.SeedHash,
+				"clientSeeds": d.Shuffle.ClientSeeds,
 			})
-		}
-	}
-
-	r.Game.OnPlayerAction = func(player *game.Player, action game.ActionType, amount int64) {
-		if r.onGameEvent != nil {
-			r.onGameEvent("player_action", map[string]interface{}{
-				"playerId": player.ID,
-				"action":   action.String(),
-				"amount":   amount,
+		case game.BlindPosted:
+			r.emit("blind_posted", map[string]interface{}{
+				"playerId": d.PlayerID,
+				"kind":     d.Kind,
+				"amount":   d.Amount,
+				"dead":     d.Dead,
+			})
+		case game.ActionTaken:
+			r.emit("player_action", map[string]interface{}{
+				"playerId": d.PlayerID,
+				"action":   d.Action.String(),
+				"amount":   d.Amount,
+				"bet":      d.Bet,
+			})
+		case game.StreetDealt:
+			r.emit("cards_dealt", map[string]interface{}{
+				"phase": d.Phase.String(),
+				"cards": d.Cards,
+				"run":   d.Run,
+			})
+			r.emit("phase_change", map[string]interface{}{
+				"phase": d.Phase.String(),
+			})
+		case game.BetReturned:
+			r.emit("bet_returned", map[string]interface{}{
+				"playerId":  d.PlayerID,
+				"seatIndex": d.SeatIndex,
+				"amount":    d.Amount,
+			})
+		case game.CardsShown:
+			r.emit("cards_shown", map[string]interface{}{
+				"playerId":  d.PlayerID,
+				"seatIndex": d.SeatIndex,
+				"cards":     d.Cards,
+			})
+		case game.CardsMucked:
+			r.emit("cards_mucked", map[string]interface{}{
+				"playerId":  d.PlayerID,
+				"seatIndex": d.SeatIndex,
+			})
+		case game.RunItOffered:
+			r.emit("run_it_offer", map[string]interface{}{
+				"playerIds": d.Offer.PlayerIDs,
+				"maxRuns":   d.Offer.MaxRuns,
+				"deadline":  d.Offer.Deadline.UnixMilli(),
+			})
+		case game.PotAwarded:
+			r.emit("pot_awarded", map[string]interface{}{
+				"run": d.Run,
+				"pot": d.Pot,
+			})
+		case game.DiscardStarted:
+			r.emit("phase_change", map[string]interface{}{
+				"phase":    game.PhaseDiscard.String(),
+				"deadline": d.Deadline.UnixMilli(),
+			})
+		case game.CardDiscarded:
+			// Which card was thrown away stays in the log.
+			r.emit("card_discarded", map[string]interface{}{
+				"playerId":  d.PlayerID,
+				"seatIndex": d.SeatIndex,
+			})
+		case game.ChipsAdded:
+			r.emit("chips_added", map[string]interface{}{
+				"playerId": d.PlayerID,
+				"amount":   d.Amount,
+				"chips":    d.Chips,
 			})
+		case game.HandEnded:
+			r.handEnded(&d.Result, d.NextSeedHash)
 		}
 	}
+}
 
-	r.Game.OnCardsDealt = func(phase game.Phase, cards []game.Card) {
-		if r.onGameEvent != nil {
-			r.onGameEvent("cards_dealt", map[string]interface{}{
-				"phase": phase.String(),
-				"cards": cards,
-			})
+func (r *Room) handEnded(result *game.HandResult, nextSeedHash string) {
+	if result.Shuffle != nil && r.onShuffleRevealed != nil {
+		r.onShuffleRevealed(*result.Shuffle)
+	}
+	r.emit("hand_complete", map[string]interface{}{
+		"handNumber":   result.HandNumber,
+		"handId":       result.HandID,
+		"winners":      result.Winners,
+		"pots":         result.Pots,
+		"shuffle":      result.Shuffle,
+		"runs":         result.Runs,
+		"showdown":     result.Showdown,
+		"nextSeedHash": nextSeedHash,
+	})
+
+	// The game lock is still held here, so the restart check has to
+	// happen off this goroutine.
+	observer := r.onHandEnded
+	if observer == nil {
+		if r.Config.AutoStart {
+			r.startHandAfter(3 * time.Second)
 		}
+		return
 	}
 
-	r.Game.OnHandComplete = func(winners map[string]int64) {
-		if r.onGameEvent != nil {
-			r.onGameEvent("hand_complete", map[string]interface{}{
-				"winners": winners,
-			})
+	stacks := make(map[string]int64, len(r.Game.Players))
+	for _, p := range r.Game.Players {
+		stacks[p.ID] = p.Chips
+	}
+	autoStart := r.Config.AutoStart
+	finished := *result
+	r.observing = true
+	go func() {
+		observer(r, finished, stacks)
+		r.mu.Lock()
+		r.observing = false
+		r.mu.Unlock()
+		if autoStart {
+			r.startHandAfter(3 * time.Second)
 		}
+	}()
+}
 
-		if r.Config.AutoStart && r.Game.CanStartHand() {
-			go func() {
-				time.Sleep(3 * time.Second)
-				r.Game.StartHand()
-			}()
-		}
-	}
+// SetHandObserver has observer told about every finished hand, with each
+// seated player's stack afterwards. It runs on its own goroutine before the
+// next hand is dealt, so it may call back into the room.
+func (r *Room) SetHandObserver(observer func(r *Room, result game.HandResult, stacks map[string]int64)) {
+	r.onHandEnded = observer
 }
 
 func (r *Room) SetEventHandler(handler func(eventType string, data interface{})) {
 	r.onGameEvent = handler
 }
 
+func (r *Room) SetShuffleRecorder(recorder func(record game.ShuffleRecord)) {
+	r.onShuffleRevealed = recorder
+}
+
+func (r *Room) SetClientSeed(playerID, seed string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Game.SetClientSeed(playerID, seed)
+}
+
 func (r *Room) AddPlayer(playerID, name string, chips int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -142,84 +339,200 @@ func (r *Room) AddPlayer(playerID, name string, chips int64) error {
 	}
 
 	if r.Config.AutoStart && r.Game.CanStartHand() {
-		go func() {
-			time.Sleep(2 * time.Second)
-			r.mu.Lock()
-			defer r.mu.Unlock()
-			if r.Game.CanStartHand() {
-				r.Game.StartHand()
-			}
-		}()
+		r.startHandAfter(2 * time.Second)
 	}
 
 	return nil
 }
 
+// startHandAfter starts the next hand once delay has passed, if the table
+// is still ready, and then has everyone's state pushed to them.
+func (r *Room) startHandAfter(delay time.Duration) {
+	go func() {
+		time.Sleep(delay)
+		r.mu.Lock()
+		if r.held || r.observing {
+			r.mu.Unlock()
+			return
+		}
+		r.applyStakesLocked()
+		started := r.Game.CanStartHand() && r.Game.StartHand() == nil
+		if started && r.holdAfterHand {
+			r.held = true
+			r.holdAfterHand = false
+		}
+		r.mu.Unlock()
+		if started {
+			r.emit("game_state", nil)
+		}
+	}()
+}
+
+// Start deals the first hand at a table set up without AutoStart, such as
+// a tournament table once everyone is seated, and keeps dealing after it.
+func (r *Room) Start() {
+	r.mu.Lock()
+	r.Config.AutoStart = true
+	r.mu.Unlock()
+	r.startHandAfter(0)
+}
+
+// Hold stops the table dealing new hands until Release. Reports whether a
+// hand is being played, which is let finish.
+func (r *Room) Hold() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.held = true
+	r.holdAfterHand = false
+	return r.Game.IsHandInProgress()
+}
+
+// Busy reports whether a hand is being played or is yet to be reported to
+// the hand observer.
+func (r *Room) Busy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.observing || r.Game.IsHandInProgress()
+}
+
+// Release lets a held table deal again. With once set it deals a single
+// hand and holds again, which is how hand-for-hand play goes.
+func (r *Room) Release(once bool) {
+	r.mu.Lock()
+	r.held = false
+	r.holdAfterHand = once
+	autoStart := r.Config.AutoStart
+	r.mu.Unlock()
+	if autoStart {
+		r.startHandAfter(0)
+	}
+}
+
+// SetStakes moves the table to new blinds and ante from its next hand.
+func (r *Room) SetStakes(smallBlind, bigBlind, ante int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextStakes = &stakes{smallBlind: smallBlind, bigBlind: bigBlind, ante: ante}
+	r.applyStakesLocked()
+}
+
+func (r *Room) applyStakesLocked() {
+	s := r.nextStakes
+	if s == nil || r.Game.SetStakes(s.smallBlind, s.bigBlind, s.ante) != nil {
+		return
+	}
+	r.Config.SmallBlind = s.smallBlind
+	r.Config.BigBlind = s.bigBlind
+	r.Config.Ante = s.ante
+	r.nextStakes = nil
+}
+
+func (r *Room) IsTournament() bool {
+	return r.Config.TournamentID != ""
+}
+
 func (r *Room) RemovePlayer(playerID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	return r.Game.RemovePlayer(playerID)
 }
 
+// Unseat takes a player and their stack off the table between hands, for
+// seating them at another one.
+func (r *Room) Unseat(playerID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Game.Unseat(playerID)
+}
+
+func (r *Room) NextBigBlind() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Game.NextBigBlind()
+}
+
 func (r *Room) ProcessAction(playerID, action string, amount int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	actionType := parseAction(action)
+	actionType, _ := game.ParseActionType(action)
 	return r.Game.ProcessAction(playerID, actionType, amount)
 }
 
-func parseAction(action string) game.ActionType {
-	switch action {
-	case "fold":
-		return game.ActionFold
-	case "check":
-		return game.ActionCheck
-	case "call":
-		return game.ActionCall
-	case "raise":
-		return game.ActionRaise
-	case "all_in", "allin":
-		return game.ActionAllIn
-	default:
-		return game.ActionNone
-	}
+func (r *Room) Discard(playerID string, index int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Game.Discard(playerID, index)
 }
 
-func (r *Room) SitOut(playerID string) {
+func (r *Room) RespondRunIt(playerID string, runs int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.Game.RespondRunIt(playerID, runs)
+}
 
-	for _, p := range r.Game.Players {
-		if p.ID == playerID {
-			p.State = game.StateSittingOut
-			break
-		}
+func (r *Room) SitOut(playerID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Sitting out would skip the blinds; tournament stacks are blinded off.
+	if r.IsTournament() {
+		return
 	}
+	r.Game.SitOut(playerID)
 }
 
 func (r *Room) SitIn(playerID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.Game.SitIn(playerID)
+}
 
-	for _, p := range r.Game.Players {
-		if p.ID == playerID && p.State == game.StateSittingOut {
-			p.State = game.StateWaiting
-			break
-		}
+func (r *Room) ShowCards(playerID string, indices []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Game.ShowCards(playerID, indices)
+}
+
+func (r *Room) SetAlwaysShow(playerID string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Game.SetAlwaysShow(playerID, enabled)
+}
+
+func (r *Room) SetStraddle(playerID string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Game.SetStraddle(playerID, enabled)
+}
+
+func (r *Room) BuyIn(playerID string, amount int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.IsTournament() {
+		return fmt.Errorf("cannot buy chips at a tournament table")
 	}
+	return r.Game.AddChips(playerID, amount)
 }
 
-func (r *Room) BuyIn(playerID string, amount int64) {
+// SetBounty shows the bounty on a player's head in a knockout tournament.
+func (r *Room) SetBounty(playerID string, bounty int64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.bounties == nil {
+		r.bounties = make(map[string]int64)
+	}
+	r.bounties[playerID] = bounty
+}
 
-	for _, p := range r.Game.Players {
-		if p.ID == playerID {
-			p.Chips += amount
-			break
-		}
+// TopUp adds chips a tournament sold a player, a rebuy or an add-on. The
+// hand just played has to be settled with the tournament first.
+func (r *Room) TopUp(playerID string, amount int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.observing {
+		return fmt.Errorf("cannot add chips during a hand")
 	}
+	return r.Game.AddChips(playerID, amount)
 }
 
 func (r *Room) GetPlayerCount() int {
@@ -228,6 +541,17 @@ func (r *Room) GetPlayerCount() int {
 	return len(r.Game.Players)
 }
 
+func (r *Room) HasPlayer(playerID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.Game.Players {
+		if p.ID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Room) IsEmpty() bool {
 	return r.GetPlayerCount() == 0
 }
@@ -248,14 +572,16 @@ func (r *Room) ToInfo() RoomInfo {
 	}
 
 	return RoomInfo{
-		ID:             r.ID,
-		Name:           r.Name,
-		SmallBlind:     r.Config.SmallBlind,
-		BigBlind:       r.Config.BigBlind,
-		MaxPlayers:     r.Config.MaxPlayers,
-		CurrentPlayers: len(r.Game.Players),
-		IsPrivate:      r.Config.IsPrivate,
-		Players:        players,
+		ID:               r.ID,
+		Name:             r.Name,
+		SmallBlind:       r.Config.SmallBlind,
+		BigBlind:         r.Config.BigBlind,
+		Variant:          r.Game.Config.Variant,
+		BettingStructure: r.Game.Config.BettingStructure.String(),
+		MaxPlayers:       r.Config.MaxPlayers,
+		CurrentPlayers:   len(r.Game.Players),
+		IsPrivate:        r.Config.IsPrivate,
+		Players:          players,
 	}
 }
 
@@ -265,21 +591,83 @@ func (r *Room) ToJSON() string {
 	return string(data)
 }
 
-func (r *Room) GetGameState() map[string]interface{} {
+// GameStateFor projects the game as viewerID sees it: their own hole cards
+// and any turned face up, never anyone else's. Spectators pass "".
+func (r *Room) GameStateFor(viewerID string) map[string]interface{} {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.gameStateLocked(viewerID)
+}
+
+// TurnFor describes playerID's options when it is their turn to act, in the
+// shape of the YourTurn message, and returns nil otherwise.
+func (r *Room) TurnFor(playerID string) map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	legal, deadline := r.Game.LegalActions(playerID)
+	if len(legal) == 0 {
+		return nil
+	}
+
+	var callAmount, minRaise, maxRaise int64
+	actions := make([]map[string]interface{}, 0, len(legal))
+	for _, a := range legal {
+		switch a.Action {
+		case game.ActionCall:
+			callAmount = a.Min
+		case game.ActionRaise:
+			minRaise, maxRaise = a.Min, a.Max
+		}
+		actions = append(actions, map[string]interface{}{
+			"action": a.Action.String(),
+			"min":    a.Min,
+			"max":    a.Max,
+		})
+	}
 
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return map[string]interface{}{
+		"playerId":      playerID,
+		"handId":        r.Game.HandID,
+		"callAmount":    callAmount,
+		"minRaise":      minRaise,
+		"maxRaise":      maxRaise,
+		"timeRemaining": remaining.Seconds(),
+		"deadline":      deadline.UnixMilli(),
+		"actions":       actions,
+	}
+}
+
+func (r *Room) gameStateLocked(viewerID string) map[string]interface{} {
 	players := make([]map[string]interface{}, 0)
 	for _, p := range r.Game.Players {
 		playerData := map[string]interface{}{
-			"playerId":   p.ID,
-			"name":       p.Name,
-			"seatIndex":  p.SeatIndex,
-			"chips":      p.Chips,
-			"currentBet": p.CurrentBet,
-			"state":      p.State,
-			"lastAction": p.LastAction.String(),
-			"isDealer":   p.IsDealer,
+			"playerId":         p.ID,
+			"name":             p.Name,
+			"seatIndex":        p.SeatIndex,
+			"chips":            p.Chips,
+			"currentBet":       p.CurrentBet,
+			"state":            p.State,
+			"lastAction":       p.LastAction.String(),
+			"isDealer":         p.IsDealer,
+			"timeBank":         p.TimeBank,
+			"straddle":         p.Straddle,
+			"missedSmallBlind": p.MissedSmallBlind,
+			"missedBigBlind":   p.MissedBigBlind,
+			"hasCards":         len(p.HoleCards) > 0 && p.State != game.StateFolded,
+			"mucked":           p.Mucked,
+			"alwaysShow":       p.AlwaysShow,
+			"mustDiscard":      r.Game.MustDiscard(p.ID),
+		}
+		if cards := p.VisibleCards(viewerID); len(cards) > 0 {
+			playerData["holeCards"] = append([]game.Card(nil), cards...)
+		}
+		if bounty, ok := r.bounties[p.ID]; ok {
+			playerData["bounty"] = bounty
 		}
 		players = append(players, playerData)
 	}
@@ -289,14 +677,31 @@ func (r *Room) GetGameState() map[string]interface{} {
 		totalPot += pot.Amount
 	}
 
+	var seedHash string
+	if r.Game.Shuffle != nil {
+		seedHash = r.Game.Shuffle.SeedHash
+	}
+
 	return map[string]interface{}{
+		"viewerId":          viewerID,
 		"phase":             r.Game.Phase.String(),
+		"handId":            r.Game.HandID,
+		"seedHash":          seedHash,
+		"nextSeedHash":      r.Game.NextSeedHash,
 		"dealerSeat":        r.Game.DealerSeat,
+		"smallBlindSeat":    r.Game.SmallBlindSeat,
+		"bigBlindSeat":      r.Game.BigBlindSeat,
+		"straddleSeat":      r.Game.StraddleSeat,
 		"currentPlayerSeat": r.Game.CurrentPlayerSeat,
+		"actionDeadline":    r.Game.ActionDeadline.UnixMilli(),
 		"currentBet":        r.Game.CurrentBet,
 		"minRaise":          r.Game.MinRaise,
+		"variant":           r.Game.Config.Variant,
+		"bettingStructure":  r.Game.Config.BettingStructure.String(),
 		"pot":               totalPot,
 		"communityCards":    r.Game.CommunityCards,
+		"boards":            r.Game.Boards,
+		"runItOffer":        r.Game.RunItOffer,
 		"players":           players,
 	}
 }
@@ -0,0 +1,48 @@
+package room
+
+import (
+	"sync"
+
+	"texas-holdem-server/internal/game"
+)
+
+const maxShuffleRecords = 10000
+
+// shuffleLog keeps the revealed shuffles of recent hands so players can
+// verify them after the room is gone. The oldest records are dropped first.
+type shuffleLog struct {
+	records map[string]game.ShuffleRecord
+	order   []string
+	limit   int
+	mu      sync.RWMutex
+}
+
+func newShuffleLog(limit int) *shuffleLog {
+	return &shuffleLog{
+		records: make(map[string]game.ShuffleRecord),
+		order:   make([]string, 0),
+		limit:   limit,
+	}
+}
+
+func (l *shuffleLog) add(record game.ShuffleRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.records[record.HandID]; !ok {
+		l.order = append(l.order, record.HandID)
+	}
+	l.records[record.HandID] = record
+
+	for len(l.order) > l.limit {
+		delete(l.records, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+func (l *shuffleLog) get(handID string) (game.ShuffleRecord, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	record, ok := l.records[handID]
+	return record, ok
+}
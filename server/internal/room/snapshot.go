@@ -0,0 +1,160 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"texas-holdem-server/internal/game"
+)
+
+const managerSnapshotVersion = 1
+
+type RoomSnapshot struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Config    RoomConfig     `json:"config"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Game      *game.Snapshot `json:"game"`
+}
+
+type managerSnapshot struct {
+	Version int             `json:"version"`
+	SavedAt time.Time       `json:"savedAt"`
+	Rooms   []*RoomSnapshot `json:"rooms"`
+}
+
+func (r *Room) Snapshot() (*RoomSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	gameSnapshot, err := r.Game.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &RoomSnapshot{
+		ID:        r.ID,
+		Name:      r.Name,
+		Config:    r.Config,
+		CreatedAt: r.CreatedAt,
+		Game:      gameSnapshot,
+	}, nil
+}
+
+// RestoreRoom brings a room back from a snapshot. A hand in progress carries
+// on with a fresh clock for whoever was to act.
+func RestoreRoom(s *RoomSnapshot) (*Room, error) {
+	if s.Game == nil {
+		return nil, fmt.Errorf("room %s has no game", s.ID)
+	}
+	g, err := game.RestoreGame(s.Game)
+	if err != nil {
+		return nil, fmt.Errorf("room %s: %v", s.ID, err)
+	}
+	g.RestartClock(time.Now())
+
+	r := &Room{
+		ID:        s.ID,
+		Name:      s.Name,
+		Config:    s.Config,
+		Game:      g,
+		CreatedAt: s.CreatedAt,
+		stopClock: make(chan struct{}),
+	}
+
+	r.setupGameCallbacks()
+	go r.runClock()
+
+	if r.Config.AutoStart && r.Game.CanStartHand() {
+		r.startHandAfter(2 * time.Second)
+	}
+	return r, nil
+}
+
+// SaveSnapshot writes every room to path, replacing the file in one step so
+// a crash mid-write leaves the previous snapshot intact. Tournament tables
+// are left out: their tournament is not saved with them.
+func (m *Manager) SaveSnapshot(path string) error {
+	m.mu.RLock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mu.RUnlock()
+
+	snapshot := managerSnapshot{
+		Version: managerSnapshotVersion,
+		SavedAt: time.Now(),
+		Rooms:   make([]*RoomSnapshot, 0, len(rooms)),
+	}
+	for _, room := range rooms {
+		if room.IsEmpty() || room.IsTournament() {
+			continue
+		}
+		rs, err := room.Snapshot()
+		if err != nil {
+			return fmt.Errorf("room %s: %v", room.ID, err)
+		}
+		snapshot.Rooms = append(snapshot.Rooms, rs)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	// Snapshots hold unrevealed seeds and deck orders.
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSnapshot restores the rooms saved at path and returns how many came
+// back. A missing file is not an error. The file is removed once loaded so
+// a later crash cannot replay the same hands twice.
+func (m *Manager) LoadSnapshot(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var snapshot managerSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return 0, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	if snapshot.Version != managerSnapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	restored := make([]*Room, 0, len(snapshot.Rooms))
+	for _, rs := range snapshot.Rooms {
+		room, err := RestoreRoom(rs)
+		if err != nil {
+			for _, r := range restored {
+				r.Close()
+			}
+			return 0, err
+		}
+		restored = append(restored, room)
+	}
+
+	m.mu.Lock()
+	for _, room := range restored {
+		m.addRoomLocked(room)
+	}
+	m.mu.Unlock()
+
+	return len(restored), os.Remove(path)
+}
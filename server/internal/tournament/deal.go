@@ -0,0 +1,249 @@
+package tournament
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Deal shares out the prizes still to be won among the players left, who
+// all have to accept it.
+type Deal struct {
+	Method     string           `json:"method"`    // "icm" or "chip"
+	Remainder  int64            `json:"remainder"` // kept back from first place for the winner of the rest
+	ProposedBy string           `json:"proposedBy"`
+	Stacks     map[string]int64 `json:"stacks"`
+	Amounts    map[string]int64 `json:"amounts"` // paid to each of them when agreed
+	Accepted   map[string]bool  `json:"accepted"`
+}
+
+func (d *Deal) agreed() bool {
+	return len(d.Accepted) == len(d.Amounts)
+}
+
+// workOut prices the deal from the stacks as they are now.
+func (t *Tournament) workOut(deal *Deal) error {
+	left := make([]*Entry, 0)
+	for _, e := range t.Entries {
+		if e.Position == 0 {
+			left = append(left, e)
+		}
+	}
+	// The places left, less any a deal has already shared out. Those that
+	// pay nothing are left off, which spares ICM the work.
+	payouts := make([]int64, len(left))
+	for i := range payouts {
+		payouts[i] = t.prizeFor(i + 1)
+	}
+	for len(payouts) > 1 && payouts[len(payouts)-1] == 0 {
+		payouts = payouts[:len(payouts)-1]
+	}
+	if deal.Remainder < 0 || deal.Remainder > payouts[0] {
+		return fmt.Errorf("the remainder must come out of the first prize")
+	}
+	payouts[0] -= deal.Remainder
+
+	stacks := make([]int64, len(left))
+	for i, e := range left {
+		stacks[i] = e.Chips
+	}
+	var amounts []int64
+	var err error
+	switch deal.Method {
+	case "icm":
+		amounts, err = ICM(stacks, payouts)
+	case "chip":
+		amounts, err = ChipChop(stacks, payouts)
+	default:
+		err = fmt.Errorf("unknown deal method")
+	}
+	if err != nil {
+		return err
+	}
+
+	deal.Stacks = make(map[string]int64, len(left))
+	deal.Amounts = make(map[string]int64, len(left))
+	deal.Accepted = make(map[string]bool, len(left))
+	for i, e := range left {
+		deal.Stacks[e.PlayerID] = stacks[i]
+		deal.Amounts[e.PlayerID] = amounts[i]
+	}
+	return nil
+}
+
+// agree settles an accepted deal. Without a remainder to play for, the
+// players left are placed by chips and collect their own bounties. Returns
+// the bounty cash won.
+func (t *Tournament) agree() map[string]int64 {
+	deal := t.Deal
+	t.Deal = nil
+	t.Agreed = deal
+
+	left := make([]*Entry, 0, len(deal.Amounts))
+	for _, e := range t.Entries {
+		if e.Position == 0 {
+			e.Deal += deal.Amounts[e.PlayerID]
+			left = append(left, e)
+		}
+	}
+	cash := make(map[string]int64)
+	if deal.Remainder > 0 {
+		return cash
+	}
+
+	sort.SliceStable(left, func(i, j int) bool { return left[i].Chips > left[j].Chips })
+	for i, e := range left {
+		e.Position = i + 1
+		won := e.Bounty
+		if i == 0 {
+			won += t.unclaimed
+			t.unclaimed = 0
+		}
+		if won > 0 {
+			cash[e.PlayerID] = won
+			e.Bounties += won
+		}
+		e.Bounty = 0
+	}
+	t.awardPrizes()
+	return cash
+}
+
+func (t *Tournament) dealEvent() event {
+	return event{t.playerIDs(), "tournament_deal", map[string]interface{}{
+		"tournamentId": t.ID,
+		"deal":         t.Deal,
+	}}
+}
+
+// ProposeDeal offers the players at the final table a share of the prizes
+// left, by ICM or by chips. Play stops while they decide.
+func (s *Service) ProposeDeal(id, playerID, method string, remainder int64) error {
+	return s.negotiate(id, playerID, func(t *Tournament) ([]event, error) {
+		if len(t.Tables) != 1 {
+			return nil, fmt.Errorf("deals are made at the final table")
+		}
+		if t.buyingOpen() {
+			return nil, fmt.Errorf("the prize pool is not settled yet")
+		}
+		if t.Deal != nil || t.Agreed != nil {
+			return nil, fmt.Errorf("a deal has already been proposed")
+		}
+
+		deal := &Deal{Method: method, Remainder: remainder, ProposedBy: playerID}
+		if err := t.workOut(deal); err != nil {
+			return nil, err
+		}
+		deal.Accepted[playerID] = true
+		t.Deal = deal
+		// A hand being played is let finish, and the deal reworked after.
+		if r := s.rooms.GetRoom(t.Tables[0]); r != nil {
+			r.Hold()
+		}
+		return []event{t.dealEvent()}, nil
+	})
+}
+
+// AcceptDeal agrees to the deal on the table, which is made once everyone
+// has.
+func (s *Service) AcceptDeal(id, playerID string) error {
+	return s.negotiate(id, playerID, func(t *Tournament) ([]event, error) {
+		if t.Deal == nil {
+			return nil, fmt.Errorf("no deal has been proposed")
+		}
+		t.Deal.Accepted[playerID] = true
+		// A hand still being played is settled first, and the deal
+		// closed or reworked then.
+		if r := s.rooms.GetRoom(t.Tables[0]); !t.Deal.agreed() || r != nil && r.Busy() {
+			return []event{t.dealEvent()}, nil
+		}
+		return s.closeDeal(t), nil
+	})
+}
+
+// RejectDeal turns the deal down, and play goes on.
+func (s *Service) RejectDeal(id, playerID string) error {
+	return s.negotiate(id, playerID, func(t *Tournament) ([]event, error) {
+		if t.Deal == nil {
+			return nil, fmt.Errorf("no deal has been proposed")
+		}
+		t.Deal = nil
+		s.releaseTables(t)
+		return []event{{t.playerIDs(), "tournament_deal_rejected", map[string]interface{}{
+			"tournamentId": t.ID,
+			"playerId":     playerID,
+		}}}, nil
+	})
+}
+
+func (s *Service) negotiate(id, playerID string, step func(t *Tournament) ([]event, error)) error {
+	t := s.Get(id)
+	if t == nil {
+		return fmt.Errorf("tournament not found")
+	}
+
+	t.mu.Lock()
+	var events []event
+	var err error
+	if e := t.entry(playerID); e == nil || e.Position != 0 || t.Status != StatusRunning {
+		err = fmt.Errorf("not playing in this tournament")
+	} else {
+		events, err = step(t)
+	}
+	t.mu.Unlock()
+
+	s.emit(events)
+	return err
+}
+
+// closeDeal pays everyone their share. The tournament is over unless they
+// kept a remainder back to play for.
+func (s *Service) closeDeal(t *Tournament) []event {
+	bounties := t.agree()
+	for id, amount := range t.Agreed.Amounts {
+		s.pay(t, id, "deal", amount)
+	}
+	for id, cash := range bounties {
+		s.pay(t, id, "bounty", cash)
+	}
+	s.payPrizes(t)
+
+	events := []event{{t.playerIDs(), "tournament_deal_agreed", map[string]interface{}{
+		"tournamentId": t.ID,
+		"deal":         t.Agreed,
+	}}}
+	if t.remaining() == 0 {
+		return append(events, s.finish(t))
+	}
+	s.releaseTables(t)
+	return append(events, event{t.playerIDs(), "tournament_standings", t.standingsInfo()})
+}
+
+// reviseDeal settles a deal on the table once the hand it waited for is
+// over. If the hand changed the stacks it was priced from, it is reworked
+// and everyone has to accept it again.
+func (s *Service) reviseDeal(t *Tournament) []event {
+	if t.Deal == nil || t.Status != StatusRunning {
+		return nil
+	}
+	changed := len(t.Deal.Stacks) != t.remaining()
+	for _, e := range t.Entries {
+		if chips, ok := t.Deal.Stacks[e.PlayerID]; e.Position == 0 && (!ok || chips != e.Chips) {
+			changed = true
+		}
+	}
+	if !changed {
+		if t.Deal.agreed() {
+			return s.closeDeal(t)
+		}
+		return nil
+	}
+
+	if err := t.workOut(t.Deal); err != nil {
+		t.Deal = nil
+		s.releaseTables(t)
+		return []event{{t.playerIDs(), "tournament_deal_rejected", map[string]interface{}{
+			"tournamentId": t.ID,
+		}}}
+	}
+	return []event{t.dealEvent()}
+}
@@ -0,0 +1,229 @@
+package tournament
+
+import (
+	"log"
+
+	"texas-holdem-server/internal/game"
+	"texas-holdem-server/internal/room"
+)
+
+// handFinished hears about every hand at every table of t. A table is
+// between hands while this runs, so its players can be moved.
+func (s *Service) handFinished(t *Tournament) func(r *room.Room, result game.HandResult, stacks map[string]int64) {
+	return func(r *room.Room, result game.HandResult, stacks map[string]int64) {
+		t.mu.Lock()
+		t.noteKnockouts(result, stacks)
+		var events []event
+		if t.HandForHand {
+			events = s.handForHandFinished(t, r.ID, stacks)
+		} else {
+			events = s.recordHand(t, stacks)
+			events = append(events, s.arrangeTables(t, []string{r.ID})...)
+			events = append(events, s.checkHandForHand(t)...)
+		}
+		events = append(events, s.reviseDeal(t)...)
+		t.mu.Unlock()
+
+		s.emit(events)
+	}
+}
+
+// handForHandFinished waits for every table to finish the round's hand.
+// Everyone busted in the round is then placed together and the tables are
+// rearranged before the next round is dealt.
+func (s *Service) handForHandFinished(t *Tournament, roomID string, stacks map[string]int64) []event {
+	// A hand that was over before hand-for-hand began counts on its own.
+	if !t.pending[roomID] {
+		return s.recordHand(t, stacks)
+	}
+
+	delete(t.pending, roomID)
+	for id, chips := range stacks {
+		t.roundStacks[id] = chips
+	}
+	if len(t.pending) > 0 {
+		return nil
+	}
+
+	events := s.recordHand(t, t.roundStacks)
+	t.roundStacks = nil
+	events = append(events, s.arrangeTables(t, append([]string(nil), t.Tables...))...)
+	return append(events, s.checkHandForHand(t)...)
+}
+
+// checkHandForHand holds every table once the field is on the bubble,
+// deals the next round while it still is, and lets the tables play on
+// freely when it bursts. A break puts all of that off until it is over.
+func (s *Service) checkHandForHand(t *Tournament) []event {
+	if t.Status != StatusRunning {
+		return nil
+	}
+
+	bubble := t.onTheBubble()
+	switch {
+	case bubble && !t.HandForHand:
+		t.HandForHand = true
+		t.pending = make(map[string]bool)
+		t.roundStacks = make(map[string]int64)
+		for _, id := range t.Tables {
+			if r := s.rooms.GetRoom(id); r != nil && r.Hold() {
+				t.pending[id] = true
+			}
+		}
+		if len(t.pending) == 0 && !t.onBreak() {
+			s.dealRound(t)
+		}
+	case bubble:
+		if !t.onBreak() {
+			s.dealRound(t)
+		}
+		return nil
+	case t.HandForHand:
+		t.HandForHand = false
+		t.pending = nil
+		s.releaseTables(t)
+	default:
+		return nil
+	}
+
+	return []event{{t.playerIDs(), "tournament_hand_for_hand", map[string]interface{}{
+		"tournamentId": t.ID,
+		"handForHand":  t.HandForHand,
+	}}}
+}
+
+// releaseTables lets every table deal freely again, unless it is a break
+// or a deal is being discussed.
+func (s *Service) releaseTables(t *Tournament) {
+	if t.onBreak() || t.Deal != nil {
+		return
+	}
+	for _, id := range t.Tables {
+		if r := s.rooms.GetRoom(id); r != nil {
+			r.Release(false)
+		}
+	}
+}
+
+// dealRound deals one hand at every table that can play one.
+func (s *Service) dealRound(t *Tournament) {
+	t.roundStacks = make(map[string]int64)
+	for _, id := range t.Tables {
+		r := s.rooms.GetRoom(id)
+		if r == nil {
+			continue
+		}
+		if r.GetPlayerCount() >= 2 {
+			t.pending[id] = true
+		}
+		r.Release(true)
+	}
+}
+
+// arrangeTables breaks the tables the field no longer needs and keeps the
+// rest within one player of each other. Players are only moved off idle
+// tables, the ones between hands; a table that has dealt again in the
+// meantime is left for after its hand.
+func (s *Service) arrangeTables(t *Tournament, idle []string) []event {
+	if t.Status != StatusRunning {
+		return nil
+	}
+
+	isIdle := make(map[string]bool, len(idle))
+	for _, id := range idle {
+		isIdle[id] = true
+	}
+	counts := t.tableCounts()
+	events := make([]event, 0)
+
+	// Break the idle table with the fewest players, which moves the fewest.
+	for len(t.Tables) > t.tablesNeeded() {
+		broken := ""
+		for _, id := range t.Tables {
+			if isIdle[id] && (broken == "" || counts[id] < counts[broken]) {
+				broken = id
+			}
+		}
+		if broken == "" {
+			break
+		}
+		for _, e := range t.seatedAt(broken) {
+			seat, ok := s.move(t, e, t.smallestTable(counts, broken), counts)
+			if !ok {
+				break
+			}
+			events = append(events, seat)
+		}
+		if counts[broken] > 0 {
+			break
+		}
+		t.removeTable(broken)
+		delete(isIdle, broken)
+		s.rooms.DeleteRoom(broken)
+	}
+
+	for _, id := range t.Tables {
+		if !isIdle[id] {
+			continue
+		}
+		for {
+			to := t.smallestTable(counts, id)
+			if to == "" || counts[id] <= counts[to]+1 {
+				break
+			}
+			seat, ok := s.move(t, s.nextToMove(t, id), to, counts)
+			if !ok {
+				break
+			}
+			events = append(events, seat)
+		}
+	}
+	return events
+}
+
+// nextToMove picks who leaves a table to balance another: the player due
+// the big blind, so nobody skips one by moving.
+func (s *Service) nextToMove(t *Tournament, roomID string) *Entry {
+	seated := t.seatedAt(roomID)
+	if len(seated) == 0 {
+		return nil
+	}
+	if r := s.rooms.GetRoom(roomID); r != nil {
+		if e := t.entry(r.NextBigBlind()); e != nil && e.RoomID == roomID {
+			return e
+		}
+	}
+	return seated[0]
+}
+
+// move takes a player and their stack from their table to another.
+func (s *Service) move(t *Tournament, e *Entry, to string, counts map[string]int) (event, bool) {
+	if e == nil || to == "" {
+		return event{}, false
+	}
+	from := s.rooms.GetRoom(e.RoomID)
+	dest := s.rooms.GetRoom(to)
+	if from == nil || dest == nil {
+		return event{}, false
+	}
+
+	chips, err := from.Unseat(e.PlayerID)
+	if err != nil {
+		return event{}, false
+	}
+	if err := dest.AddPlayer(e.PlayerID, e.Name, chips); err != nil {
+		log.Printf("Tournament %s could not move %s to %s: %v", t.ID, e.PlayerID, to, err)
+		// Nobody else can sit at a tournament table, so the seat they
+		// left is still free.
+		from.AddPlayer(e.PlayerID, e.Name, chips)
+		return event{}, false
+	}
+
+	seat := Seat{TournamentID: t.ID, RoomID: to, FromRoomID: e.RoomID}
+	counts[e.RoomID]--
+	counts[to]++
+	e.RoomID = to
+	e.Chips = chips
+	s.showBounty(t, e)
+	return event{[]string{e.PlayerID}, "tournament_seat", seat}, true
+}
@@ -0,0 +1,152 @@
+package tournament
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MaxICMPlayers bounds the ICM calculator, whose work grows with every
+// player and paid place.
+const MaxICMPlayers = 20
+
+func checkDealInput(stacks, payouts []int64) error {
+	if len(stacks) == 0 {
+		return fmt.Errorf("need at least one stack")
+	}
+	for _, s := range stacks {
+		if s <= 0 {
+			return fmt.Errorf("invalid stack")
+		}
+	}
+	for _, p := range payouts {
+		if p < 0 {
+			return fmt.Errorf("invalid payout")
+		}
+	}
+	return nil
+}
+
+// ICM shares out payouts, first place first, by the Independent Chip
+// Model: a player's chance of finishing first is their share of the chips,
+// and of each later place their share of the chips of those left. Returns
+// what each stack is worth, in whole chips that add up to the payouts.
+func ICM(stacks, payouts []int64) ([]int64, error) {
+	if len(stacks) > MaxICMPlayers {
+		return nil, fmt.Errorf("need between 1 and %d stacks", MaxICMPlayers)
+	}
+	if err := checkDealInput(stacks, payouts); err != nil {
+		return nil, err
+	}
+	if len(payouts) > len(stacks) {
+		payouts = payouts[:len(stacks)]
+	}
+
+	var chips int64
+	for _, s := range stacks {
+		chips += s
+	}
+
+	// Walk the orders players can finish in, one paid place at a time;
+	// placed is who has finished in the places above.
+	equity := make([]float64, len(stacks))
+	level := map[uint32]float64{0: 1}
+	for _, payout := range payouts {
+		masks := make([]uint32, 0, len(level))
+		for mask := range level {
+			masks = append(masks, mask)
+		}
+		sort.Slice(masks, func(i, j int) bool { return masks[i] < masks[j] })
+
+		next := make(map[uint32]float64)
+		for _, placed := range masks {
+			left := chips
+			for i, s := range stacks {
+				if placed&(1<<uint(i)) != 0 {
+					left -= s
+				}
+			}
+			for i, s := range stacks {
+				if placed&(1<<uint(i)) != 0 {
+					continue
+				}
+				p := level[placed] * float64(s) / float64(left)
+				equity[i] += p * float64(payout)
+				next[placed|1<<uint(i)] += p
+			}
+		}
+		level = next
+	}
+
+	var total int64
+	for _, p := range payouts {
+		total += p
+	}
+	return shareOut(total, equity), nil
+}
+
+// ChipChop gives everyone the smallest of the prizes left and splits the
+// rest by chips.
+func ChipChop(stacks, payouts []int64) ([]int64, error) {
+	if err := checkDealInput(stacks, payouts); err != nil {
+		return nil, err
+	}
+	if len(payouts) > len(stacks) {
+		payouts = payouts[:len(stacks)]
+	}
+
+	// A remainder kept back out of first place can leave it the smallest.
+	var total, least int64
+	for i, p := range payouts {
+		total += p
+		if i == 0 || p < least {
+			least = p
+		}
+	}
+	if len(payouts) < len(stacks) {
+		least = 0
+	}
+
+	weights := make([]float64, len(stacks))
+	for i, s := range stacks {
+		weights[i] = float64(s)
+	}
+	amounts := shareOut(total-least*int64(len(stacks)), weights)
+	for i := range amounts {
+		amounts[i] += least
+	}
+	return amounts, nil
+}
+
+// shareOut divides total in proportion to weights, in whole chips. Chips
+// left over by rounding down go to the largest fractions.
+func shareOut(total int64, weights []float64) []int64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	amounts := make([]int64, len(weights))
+	if sum == 0 {
+		return amounts
+	}
+
+	fractions := make([]float64, len(weights))
+	var given int64
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		amounts[i] = int64(math.Floor(exact))
+		fractions[i] = exact - float64(amounts[i])
+		given += amounts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fractions[order[a]] > fractions[order[b]] })
+	for i := 0; given < total; i = (i + 1) % len(order) {
+		amounts[order[i]]++
+		given++
+	}
+	return amounts
+}
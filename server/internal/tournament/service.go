@@ -0,0 +1,707 @@
+package tournament
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"texas-holdem-server/internal/room"
+)
+
+// Wallet holds players' chips outside of play. user.Service implements it.
+type Wallet interface {
+	AddChips(userID string, amount int64, reason string) error
+	DeductChips(userID string, amount int64, reason string) error
+}
+
+// Seat sends a player to their table, or moves them to another one.
+type Seat struct {
+	TournamentID string `json:"tournamentId"`
+	RoomID       string `json:"roomId"`
+	FromRoomID   string `json:"fromRoomId,omitempty"`
+}
+
+type event struct {
+	playerIDs []string
+	eventType string
+	data      interface{}
+}
+
+// payRetry is how long a payment the wallet refused waits to be tried again.
+const payRetry = time.Minute
+
+type Service struct {
+	rooms       *room.Manager
+	wallet      Wallet
+	tournaments map[string]*Tournament
+	mu          sync.RWMutex
+	stopChan    chan struct{}
+
+	onEvent func(playerIDs []string, eventType string, data interface{})
+}
+
+func NewService(rooms *room.Manager, wallet Wallet) *Service {
+	s := &Service{
+		rooms:       rooms,
+		wallet:      wallet,
+		tournaments: make(map[string]*Tournament),
+		stopChan:    make(chan struct{}),
+	}
+
+	go s.clockRoutine()
+
+	return s
+}
+
+func (s *Service) Stop() {
+	close(s.stopChan)
+}
+
+// SetEventHandler receives every tournament message along with the players
+// it is for.
+func (s *Service) SetEventHandler(handler func(playerIDs []string, eventType string, data interface{})) {
+	s.onEvent = handler
+}
+
+func (s *Service) Create(config Config) (*Tournament, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	t := newTournament(uuid.New().String()[:8], config)
+
+	s.mu.Lock()
+	s.tournaments[t.ID] = t
+	s.mu.Unlock()
+
+	return t, nil
+}
+
+func (s *Service) Get(id string) *Tournament {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tournaments[id]
+}
+
+func (s *Service) List() []Info {
+	s.mu.RLock()
+	tournaments := make([]*Tournament, 0, len(s.tournaments))
+	for _, t := range s.tournaments {
+		tournaments = append(tournaments, t)
+	}
+	s.mu.RUnlock()
+
+	infos := make([]Info, 0, len(tournaments))
+	for _, t := range tournaments {
+		infos = append(infos, t.ToInfo())
+	}
+	return infos
+}
+
+// Register takes the buy-in and fee from the player's wallet. A tournament
+// without a start time starts as soon as its last seat is taken. During
+// late registration the entry is seated straight away, and a player who
+// has busted may enter again if re-entries are allowed.
+func (s *Service) Register(id, playerID, name string) error {
+	t := s.Get(id)
+	if t == nil {
+		return fmt.Errorf("tournament not found")
+	}
+
+	t.mu.Lock()
+	events, err := s.register(t, playerID, name)
+	t.mu.Unlock()
+
+	s.emit(events)
+	return err
+}
+
+func (s *Service) register(t *Tournament, playerID, name string) ([]event, error) {
+	late := t.lateRegistration()
+	if t.Status != StatusRegistering && !late {
+		return nil, fmt.Errorf("registration is closed")
+	}
+	kind := "entry"
+	if e := t.entry(playerID); e != nil {
+		if e.Position == 0 {
+			return nil, fmt.Errorf("already registered")
+		}
+		if t.entriesOf(playerID) > t.Config.ReEntries {
+			return nil, fmt.Errorf("no re-entries left")
+		}
+		kind = "re-entry"
+	}
+	if len(t.Entries) >= t.Config.Players {
+		return nil, fmt.Errorf("tournament is full")
+	}
+	if err := s.wallet.DeductChips(playerID, t.Config.BuyIn+t.Config.Fee, "tournament "+kind+" "+t.ID); err != nil {
+		return nil, err
+	}
+
+	if late {
+		roomID, err := s.seatLate(t, playerID, name)
+		if err != nil {
+			s.wallet.AddChips(playerID, t.Config.BuyIn+t.Config.Fee, "tournament refund "+t.ID)
+			return nil, err
+		}
+		e := t.addEntry(playerID, name)
+		e.RoomID = roomID
+		s.showBounty(t, e)
+		t.audit(playerID, kind, t.Config.BuyIn, t.Config.Fee, e.Chips)
+		return []event{
+			{[]string{playerID}, "tournament_seat", Seat{TournamentID: t.ID, RoomID: roomID}},
+			{[]string{playerID}, "tournament_level", t.levelInfo()},
+			{t.playerIDs(), "tournament_standings", t.standingsInfo()},
+		}, nil
+	}
+
+	t.addEntry(playerID, name)
+	t.audit(playerID, kind, t.Config.BuyIn, t.Config.Fee, t.Config.StartingStack)
+	events := []event{{t.playerIDs(), "tournament_standings", t.standingsInfo()}}
+	if len(t.Entries) < t.Config.Players || t.Config.StartAt != 0 {
+		return events, nil
+	}
+
+	started, err := s.start(t)
+	if err != nil {
+		// Give the last seat back so the next registration can try again.
+		t.Entries = t.Entries[:len(t.Entries)-1]
+		s.refund(t, playerID)
+		return nil, fmt.Errorf("cannot start tournament: %v", err)
+	}
+	return append(events, started...), nil
+}
+
+// seatLate sits a late entry at the table with the fewest players, opening
+// another when they are all full. The tables are balanced again as their
+// hands finish.
+func (s *Service) seatLate(t *Tournament, playerID, name string) (string, error) {
+	counts := t.tableCounts()
+	to := t.smallestTable(counts, "")
+	if to != "" && counts[to] < t.Config.tableSize() {
+		r := s.rooms.GetRoom(to)
+		if r == nil {
+			return "", fmt.Errorf("table not found")
+		}
+		return to, r.AddPlayer(playerID, name, t.Config.StartingStack)
+	}
+
+	r, err := s.openTable(t, t.tablesOpened+1)
+	if err != nil {
+		return "", err
+	}
+	if err := r.AddPlayer(playerID, name, t.Config.StartingStack); err != nil {
+		s.rooms.DeleteRoom(r.ID)
+		return "", err
+	}
+	t.tablesOpened++
+	t.Tables = append(t.Tables, r.ID)
+	// Hand-for-hand deals it in with the next round.
+	if t.onBreak() || t.HandForHand {
+		r.Hold()
+	}
+	r.Start()
+	return r.ID, nil
+}
+
+// refund gives a player back their buy-in and fee.
+func (s *Service) refund(t *Tournament, playerID string) error {
+	if err := s.wallet.AddChips(playerID, t.Config.BuyIn+t.Config.Fee, "tournament refund "+t.ID); err != nil {
+		return err
+	}
+	t.audit(playerID, "refund", -t.Config.BuyIn, -t.Config.Fee, 0)
+	return nil
+}
+
+// Rebuy sells a player still in another starting stack, at the buy-in and
+// fee, while they have no more than one during the rebuy period.
+func (s *Service) Rebuy(id, playerID string) error {
+	return s.purchase(id, playerID, s.rebuy)
+}
+
+// AddOn sells every player still in the add-on once, at the first break.
+func (s *Service) AddOn(id, playerID string) error {
+	return s.purchase(id, playerID, s.addOn)
+}
+
+func (s *Service) purchase(id, playerID string, buy func(t *Tournament, e *Entry) ([]event, error)) error {
+	t := s.Get(id)
+	if t == nil {
+		return fmt.Errorf("tournament not found")
+	}
+
+	t.mu.Lock()
+	var events []event
+	var err error
+	if e := t.entry(playerID); e == nil || e.Position != 0 || t.Status != StatusRunning {
+		err = fmt.Errorf("not playing in this tournament")
+	} else {
+		events, err = buy(t, e)
+	}
+	t.mu.Unlock()
+
+	s.emit(events)
+	return err
+}
+
+func (s *Service) rebuy(t *Tournament, e *Entry) ([]event, error) {
+	if !t.rebuyPeriod() {
+		return nil, fmt.Errorf("the rebuy period is over")
+	}
+	if !t.canRebuy(e) {
+		return nil, fmt.Errorf("no rebuys left")
+	}
+	if e.Chips > t.Config.StartingStack {
+		return nil, fmt.Errorf("too many chips to rebuy")
+	}
+	return s.buyChips(t, e, "rebuy", t.Config.BuyIn, t.Config.Fee, t.Config.StartingStack)
+}
+
+func (s *Service) addOn(t *Tournament, e *Entry) ([]event, error) {
+	if !t.addOnPeriod() {
+		return nil, fmt.Errorf("add-ons are sold at the first break")
+	}
+	if e.AddOn {
+		return nil, fmt.Errorf("already took the add-on")
+	}
+	return s.buyChips(t, e, "add-on", t.Config.AddOnCost, 0, t.Config.AddOnChips)
+}
+
+// buyChips takes the price from the player's wallet and puts the chips on
+// their stack at the table.
+func (s *Service) buyChips(t *Tournament, e *Entry, kind string, amount, fee, chips int64) ([]event, error) {
+	r := s.rooms.GetRoom(e.RoomID)
+	if r == nil {
+		return nil, fmt.Errorf("table not found")
+	}
+	if err := s.wallet.DeductChips(e.PlayerID, amount+fee, "tournament "+kind+" "+t.ID); err != nil {
+		return nil, err
+	}
+	if err := r.TopUp(e.PlayerID, chips); err != nil {
+		s.wallet.AddChips(e.PlayerID, amount+fee, "tournament refund "+t.ID)
+		return nil, err
+	}
+
+	e.Chips += chips
+	switch kind {
+	case "rebuy":
+		e.Rebuys++
+		e.Bounty += t.Config.Bounty
+		s.showBounty(t, e)
+	case "add-on":
+		e.AddOn = true
+	}
+	t.audit(e.PlayerID, kind, amount, fee, chips)
+	return []event{{t.playerIDs(), "tournament_standings", t.standingsInfo()}}, nil
+}
+
+// Unregister refunds a player who changes their mind before the start.
+func (s *Service) Unregister(id, playerID string) error {
+	t := s.Get(id)
+	if t == nil {
+		return fmt.Errorf("tournament not found")
+	}
+
+	t.mu.Lock()
+	if t.Status != StatusRegistering {
+		t.mu.Unlock()
+		return fmt.Errorf("tournament has started")
+	}
+	index := -1
+	for i, e := range t.Entries {
+		if e.PlayerID == playerID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		t.mu.Unlock()
+		return fmt.Errorf("not registered")
+	}
+	if err := s.refund(t, playerID); err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	t.Entries = append(t.Entries[:index], t.Entries[index+1:]...)
+	events := []event{{append(t.playerIDs(), playerID), "tournament_standings", t.standingsInfo()}}
+	t.mu.Unlock()
+
+	s.emit(events)
+	return nil
+}
+
+// start draws seats at random, spreads the field evenly over as few
+// tables as will hold it and deals the first hands.
+func (s *Service) start(t *Tournament) ([]event, error) {
+	size := t.Config.tableSize()
+	tables := make([]*room.Room, 0, (len(t.Entries)+size-1)/size)
+	closeTables := func() {
+		for _, r := range tables {
+			s.rooms.DeleteRoom(r.ID)
+		}
+	}
+	for len(tables) < cap(tables) {
+		r, err := s.openTable(t, len(tables)+1)
+		if err != nil {
+			closeTables()
+			return nil, err
+		}
+		tables = append(tables, r)
+	}
+
+	seats := make([]*room.Room, len(t.Entries))
+	for n, i := range rand.Perm(len(t.Entries)) {
+		e := t.Entries[i]
+		seats[i] = tables[n%len(tables)]
+		if err := seats[i].AddPlayer(e.PlayerID, e.Name, e.Chips); err != nil {
+			closeTables()
+			return nil, err
+		}
+	}
+
+	events := make([]event, 0, len(t.Entries)+3)
+	for i, e := range t.Entries {
+		e.RoomID = seats[i].ID
+		s.showBounty(t, e)
+		events = append(events, event{[]string{e.PlayerID}, "tournament_seat", Seat{TournamentID: t.ID, RoomID: e.RoomID}})
+	}
+	t.Tables = make([]string, len(tables))
+	for i, r := range tables {
+		t.Tables[i] = r.ID
+	}
+	t.tablesOpened = len(tables)
+	t.Status = StatusRunning
+	t.StartedAt = time.Now()
+	t.LevelEndsAt = t.StartedAt.Add(time.Duration(t.Config.Levels[0].Minutes) * time.Minute)
+	for _, r := range tables {
+		r.Start()
+	}
+
+	ids := t.playerIDs()
+	return append(events,
+		event{ids, "tournament_started", map[string]interface{}{"tournamentId": t.ID, "tables": t.Tables}},
+		event{ids, "tournament_level", t.levelInfo()},
+		event{ids, "tournament_standings", t.standingsInfo()},
+	), nil
+}
+
+// openTable creates a table at the current level. Hands there are dealt
+// once Start is called on it.
+func (s *Service) openTable(t *Tournament, number int) (*room.Room, error) {
+	level := t.stakes()
+	r, err := s.rooms.CreateRoom(room.RoomConfig{
+		SmallBlind:       level.SmallBlind,
+		BigBlind:         level.BigBlind,
+		Ante:             level.Ante,
+		MaxPlayers:       t.Config.tableSize(),
+		MinPlayers:       2,
+		IsPrivate:        true,
+		ActionTimeout:    t.Config.ActionTimeout,
+		Variant:          t.Config.Variant,
+		BettingStructure: t.Config.BettingStructure,
+		TournamentID:     t.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Name = fmt.Sprintf("%s - Table %d", t.Config.Name, number)
+	r.SetHandObserver(s.handFinished(t))
+	return r, nil
+}
+
+// startScheduled starts a tournament whose start time has come, or calls
+// it off and refunds everyone if too few registered.
+func (s *Service) startScheduled(t *Tournament) []event {
+	if len(t.Entries) >= 2 {
+		events, err := s.start(t)
+		if err == nil {
+			return events
+		}
+		log.Printf("Tournament %s could not start: %v", t.ID, err)
+	}
+
+	return s.cancel(t)
+}
+
+// cancel calls off a tournament that has not started and refunds everyone.
+func (s *Service) cancel(t *Tournament) []event {
+	for _, e := range t.Entries {
+		if err := s.refund(t, e.PlayerID); err != nil {
+			log.Printf("Tournament %s could not refund %s: %v", t.ID, e.PlayerID, err)
+		}
+	}
+	t.Status = StatusCancelled
+	return []event{{t.playerIDs(), "tournament_cancelled", map[string]interface{}{"tournamentId": t.ID}}}
+}
+
+// Shutdown settles every tournament that has not finished, as tournaments
+// are not kept over a restart. Registrations are refunded, and running
+// tournaments end where they stand.
+func (s *Service) Shutdown() {
+	s.mu.RLock()
+	tournaments := make([]*Tournament, 0, len(s.tournaments))
+	for _, t := range s.tournaments {
+		tournaments = append(tournaments, t)
+	}
+	s.mu.RUnlock()
+
+	for _, t := range tournaments {
+		t.mu.Lock()
+		var events []event
+		switch t.Status {
+		case StatusRegistering:
+			events = s.cancel(t)
+		case StatusRunning:
+			events = s.settle(t)
+		}
+		t.mu.Unlock()
+
+		s.emit(events)
+	}
+}
+
+// settle ends a running tournament with a chip chop of the prizes left
+// among the players still in, who collect their own bounties. A hand being
+// played does not count.
+func (s *Service) settle(t *Tournament) []event {
+	// Nothing more can be bought, so whoever is waiting on a rebuy is out.
+	t.Status = StatusFinished
+	out := make(map[string]int64)
+	for _, e := range t.Entries {
+		if e.Position == 0 && e.Chips == 0 {
+			out[e.PlayerID] = 0
+		}
+	}
+	for _, e := range t.recordHand(out) {
+		for id, cash := range t.claimBounty(e) {
+			s.pay(t, id, "bounty", cash)
+		}
+	}
+
+	if t.remaining() > 0 {
+		deal := &Deal{Method: "chip"}
+		if err := t.workOut(deal); err != nil {
+			log.Printf("Tournament %s could not be settled: %v", t.ID, err)
+		} else {
+			t.Deal = deal
+			return s.closeDeal(t)
+		}
+	}
+	s.payPrizes(t)
+	return []event{s.finish(t)}
+}
+
+// recordHand places everyone who busted and takes them off their tables,
+// pays whatever prizes and bounties are due and finishes the tournament
+// once one player is left.
+func (s *Service) recordHand(t *Tournament, stacks map[string]int64) []event {
+	if t.Status != StatusRunning {
+		return nil
+	}
+
+	placed := t.recordHand(stacks)
+	s.payPrizes(t)
+	ids := t.playerIDs()
+	events := make([]event, 0, len(placed)+2)
+	for _, e := range placed {
+		if r := s.rooms.GetRoom(e.RoomID); r != nil && e.Position > 1 {
+			r.RemovePlayer(e.PlayerID)
+			e.RoomID = ""
+		}
+		bounties := t.claimBounty(e)
+		for id, cash := range bounties {
+			s.pay(t, id, "bounty", cash)
+		}
+		for _, id := range e.knockedOutBy {
+			if h := t.entry(id); h != nil {
+				s.showBounty(t, h)
+			}
+		}
+		if e.Position > 1 {
+			events = append(events, event{ids, "tournament_eliminated", map[string]interface{}{
+				"tournamentId": t.ID,
+				"playerId":     e.PlayerID,
+				"position":     e.Position,
+				"prize":        e.Prize,
+				"knockedOutBy": e.knockedOutBy,
+				"bounties":     bounties,
+			}})
+		}
+	}
+	events = append(events, event{ids, "tournament_standings", t.standingsInfo()})
+
+	if t.remaining() == 0 {
+		events = append(events, s.finish(t))
+	}
+	return events
+}
+
+// finish closes the tables of a tournament everyone has been placed in.
+func (s *Service) finish(t *Tournament) event {
+	t.Status = StatusFinished
+	t.HandForHand = false
+	t.Deal = nil
+	for _, id := range t.Tables {
+		s.rooms.DeleteRoom(id)
+	}
+	t.Tables = nil
+	return event{t.playerIDs(), "tournament_finished", map[string]interface{}{
+		"tournamentId": t.ID,
+		"standings":    t.Standings(),
+	}}
+}
+
+// showBounty puts the bounty on a player's head next to them at their
+// table.
+func (s *Service) showBounty(t *Tournament, e *Entry) {
+	if t.Config.Bounty == 0 {
+		return
+	}
+	if r := s.rooms.GetRoom(e.RoomID); r != nil {
+		r.SetBounty(e.PlayerID, e.Bounty)
+	}
+}
+
+// payPrizes pays every prize awarded that has not been paid yet, and tries
+// again the bounties and deals the wallet refused. Reports whether any prize
+// was paid.
+func (s *Service) payPrizes(t *Tournament) bool {
+	paid := false
+	for _, e := range t.Entries {
+		if e.Prize == 0 || e.paid {
+			continue
+		}
+		if s.credit(t, e.PlayerID, "prize", e.Prize) {
+			e.paid = true
+			paid = true
+		}
+	}
+
+	unpaid := t.unpaid
+	t.unpaid = nil
+	for _, p := range unpaid {
+		s.pay(t, p.playerID, p.kind, p.amount)
+	}
+	return paid
+}
+
+// pay credits a player with a bounty or their share of a deal. If the
+// wallet refuses it, it is kept to be tried again.
+func (s *Service) pay(t *Tournament, playerID, kind string, amount int64) {
+	if !s.credit(t, playerID, kind, amount) {
+		t.unpaid = append(t.unpaid, payment{playerID, kind, amount})
+	}
+}
+
+func (s *Service) credit(t *Tournament, playerID, kind string, amount int64) bool {
+	if err := s.wallet.AddChips(playerID, amount, "tournament "+kind+" "+t.ID); err != nil {
+		log.Printf("Tournament %s could not pay %s a %s of %d: %v", t.ID, playerID, kind, amount, err)
+		return false
+	}
+	return true
+}
+
+func (s *Service) clockRoutine() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case now := <-ticker.C:
+			s.tickClock(now)
+		}
+	}
+}
+
+// tickClock starts scheduled tournaments and moves every tournament whose
+// level is up on to the next one.
+func (s *Service) tickClock(now time.Time) {
+	s.mu.RLock()
+	tournaments := make([]*Tournament, 0, len(s.tournaments))
+	for _, t := range s.tournaments {
+		tournaments = append(tournaments, t)
+	}
+	s.mu.RUnlock()
+
+	for _, t := range tournaments {
+		t.mu.Lock()
+		var events []event
+		if t.Status == StatusRegistering && t.Config.StartAt != 0 && now.UnixMilli() >= t.Config.StartAt {
+			events = s.startScheduled(t)
+		}
+		if t.advanceLevel(now) {
+			events = append(events, s.levelChanged(t)...)
+		}
+		// Payments refused are retried at every level too, but a finished
+		// tournament has no more levels.
+		if t.owing() && !now.Before(t.payRetryAt) {
+			t.payRetryAt = now.Add(payRetry)
+			s.payPrizes(t)
+		}
+		t.mu.Unlock()
+
+		s.emit(events)
+	}
+}
+
+// levelChanged raises the blinds, which the tables pick up from their next
+// hand, and stops or resumes the dealing for a break. Whoever is still
+// waiting on a rebuy when the rebuy period ends is out, and prizes held
+// back while the pool could grow are paid once it cannot.
+func (s *Service) levelChanged(t *Tournament) []event {
+	level := t.stakes()
+	for _, id := range t.Tables {
+		if r := s.rooms.GetRoom(id); r != nil {
+			r.SetStakes(level.SmallBlind, level.BigBlind, level.Ante)
+			if t.onBreak() {
+				r.Hold()
+			}
+		}
+	}
+	events := []event{{t.playerIDs(), "tournament_level", t.levelInfo()}}
+
+	if !t.onBreak() && t.Config.Levels[t.Level-1].Break {
+		if !t.HandForHand {
+			s.releaseTables(t)
+		} else if len(t.pending) == 0 {
+			s.dealRound(t)
+		}
+	}
+
+	if !t.rebuyPeriod() {
+		// Those at a table playing a hand go out when it finishes.
+		out := make(map[string]int64)
+		for _, e := range t.Entries {
+			if e.Position != 0 || e.Chips != 0 {
+				continue
+			}
+			if r := s.rooms.GetRoom(e.RoomID); r != nil {
+				if _, err := r.Unseat(e.PlayerID); err == nil {
+					out[e.PlayerID] = 0
+				}
+			}
+		}
+		if len(out) > 0 {
+			return append(events, s.recordHand(t, out)...)
+		}
+	}
+	t.awardPrizes()
+	if s.payPrizes(t) {
+		events = append(events, event{t.playerIDs(), "tournament_standings", t.standingsInfo()})
+	}
+	return events
+}
+
+func (s *Service) emit(events []event) {
+	if s.onEvent == nil {
+		return
+	}
+	for _, e := range events {
+		s.onEvent(e.playerIDs, e.eventType, e.data)
+	}
+}
@@ -0,0 +1,708 @@
+package tournament
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"texas-holdem-server/internal/game"
+)
+
+// Level is one step of the blind schedule. The last level lasts until the
+// tournament is over. No hands are dealt during a break, which keeps the
+// blinds of the level before it.
+type Level struct {
+	SmallBlind int64 `json:"smallBlind"`
+	BigBlind   int64 `json:"bigBlind"`
+	Ante       int64 `json:"ante"`
+	Minutes    int   `json:"minutes"`
+	Break      bool  `json:"break,omitempty"`
+}
+
+type Config struct {
+	Name             string  `json:"name"`
+	Players          int     `json:"players"`             // most entries; a Sit-and-Go starts once this many have registered
+	TableSize        int     `json:"tableSize,omitempty"` // seats per table, 0 for everyone at one table
+	StartAt          int64   `json:"startAt,omitempty"`   // scheduled start in unix millis, 0 to start when full
+	BuyIn            int64   `json:"buyIn"`               // goes to the prize pool and bounty
+	Fee              int64   `json:"fee"`                 // kept by the house
+	StartingStack    int64   `json:"startingStack"`
+	Levels           []Level `json:"levels"`
+	Payouts          []int   `json:"payouts"`                    // percent of the prize pool by finishing place
+	LateRegistration int     `json:"lateRegistration,omitempty"` // registration stays open through this level
+	ReEntries        int     `json:"reEntries,omitempty"`        // times a busted player may enter again during late registration
+	RebuyLevel       int     `json:"rebuyLevel,omitempty"`       // rebuys at the buy-in and fee are allowed through this level
+	MaxRebuys        int     `json:"maxRebuys,omitempty"`        // per entry, 0 for no limit
+	AddOnCost        int64   `json:"addOnCost,omitempty"`        // goes to the prize pool
+	AddOnChips       int64   `json:"addOnChips,omitempty"`       // sold once per entry at the first break, 0 for no add-on
+	Bounty           int64   `json:"bounty,omitempty"`           // of every buy-in, put on the player's head
+	BountyCash       int     `json:"bountyCash,omitempty"`       // percent of a bounty paid at once, the rest goes on the eliminator's head
+	ActionTimeout    int     `json:"actionTimeout"`
+	Variant          string  `json:"variant,omitempty"`
+	BettingStructure string  `json:"bettingStructure,omitempty"`
+}
+
+func DefaultSitAndGo() Config {
+	return Config{
+		Name:          "Sit & Go",
+		Players:       6,
+		BuyIn:         1000,
+		Fee:           100,
+		StartingStack: 1500,
+		Levels: []Level{
+			{SmallBlind: 10, BigBlind: 20, Minutes: 5},
+			{SmallBlind: 15, BigBlind: 30, Minutes: 5},
+			{SmallBlind: 25, BigBlind: 50, Minutes: 5},
+			{SmallBlind: 50, BigBlind: 100, Minutes: 5},
+			{SmallBlind: 75, BigBlind: 150, Ante: 15, Minutes: 5},
+			{SmallBlind: 100, BigBlind: 200, Ante: 25, Minutes: 5},
+			{SmallBlind: 150, BigBlind: 300, Ante: 30, Minutes: 5},
+			{SmallBlind: 200, BigBlind: 400, Ante: 50, Minutes: 5},
+		},
+		Payouts:       []int{65, 35},
+		ActionTimeout: 20,
+	}
+}
+
+func (c Config) tableSize() int {
+	if c.TableSize > 0 {
+		return c.TableSize
+	}
+	return c.Players
+}
+
+// addOnLevel is the index of the first break, where the add-on is sold,
+// or -1 without one.
+func (c Config) addOnLevel() int {
+	for i, l := range c.Levels {
+		if l.Break {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c Config) Validate() error {
+	if c.Players < 2 {
+		return fmt.Errorf("a tournament needs at least 2 players")
+	}
+	if size := c.tableSize(); size < 2 || size > 9 {
+		return fmt.Errorf("tables must seat between 2 and 9 players")
+	}
+	if c.BuyIn < 0 || c.Fee < 0 {
+		return fmt.Errorf("invalid buy-in")
+	}
+	if c.StartingStack <= 0 {
+		return fmt.Errorf("invalid starting stack")
+	}
+	if len(c.Levels) == 0 {
+		return fmt.Errorf("no blind levels")
+	}
+	if c.Levels[0].Break {
+		return fmt.Errorf("cannot start on a break")
+	}
+	for i, l := range c.Levels {
+		if l.Minutes <= 0 || !l.Break && (l.SmallBlind <= 0 || l.BigBlind < l.SmallBlind || l.Ante < 0) {
+			return fmt.Errorf("invalid blind level %d", i+1)
+		}
+	}
+	if c.LateRegistration < 0 || c.LateRegistration > len(c.Levels) || c.RebuyLevel < 0 || c.RebuyLevel > len(c.Levels) {
+		return fmt.Errorf("no such blind level")
+	}
+	if c.ReEntries < 0 || c.MaxRebuys < 0 {
+		return fmt.Errorf("invalid number of re-entries or rebuys")
+	}
+	if c.ReEntries > 0 && c.LateRegistration == 0 {
+		return fmt.Errorf("re-entry needs late registration")
+	}
+	if c.AddOnCost < 0 || c.AddOnChips < 0 {
+		return fmt.Errorf("invalid add-on")
+	}
+	if c.AddOnChips > 0 && c.addOnLevel() < 0 {
+		return fmt.Errorf("an add-on needs a break")
+	}
+	if c.Bounty < 0 || c.Bounty > c.BuyIn {
+		return fmt.Errorf("the bounty must be part of the buy-in")
+	}
+	if c.Bounty > 0 && (c.BountyCash <= 0 || c.BountyCash > 100) {
+		return fmt.Errorf("bounty cash must be between 1%% and 100%%")
+	}
+	if len(c.Payouts) == 0 || len(c.Payouts) > c.Players {
+		return fmt.Errorf("payouts must cover between 1 and %d places", c.Players)
+	}
+	total := 0
+	for _, p := range c.Payouts {
+		if p <= 0 {
+			return fmt.Errorf("invalid payout")
+		}
+		total += p
+	}
+	if total != 100 {
+		return fmt.Errorf("payouts add up to %d%%, not 100%%", total)
+	}
+	if _, err := game.VariantByName(c.Variant); err != nil {
+		return err
+	}
+	if _, err := game.ParseBettingStructure(c.BettingStructure); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Status string
+
+const (
+	StatusRegistering Status = "registering"
+	StatusRunning     Status = "running"
+	StatusFinished    Status = "finished"
+	StatusCancelled   Status = "cancelled"
+)
+
+// Entry is one buy-in. A player who re-enters has an entry for each time.
+type Entry struct {
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+	Chips    int64  `json:"chips"`
+	RoomID   string `json:"roomId,omitempty"`   // the table they are at
+	Position int    `json:"position,omitempty"` // finishing place, 0 while still playing
+	Prize    int64  `json:"prize,omitempty"`
+	Rebuys   int    `json:"rebuys,omitempty"`
+	AddOn    bool   `json:"addOn,omitempty"`
+	Bounty   int64  `json:"bounty,omitempty"`      // on their head
+	Bounties int64  `json:"bountiesWon,omitempty"` // cash won for knockouts
+	Deal     int64  `json:"deal,omitempty"`        // their share of a deal
+	paid     bool
+
+	// Who won the pots this entry last lost all its chips in.
+	knockedOutBy []string
+}
+
+// Purchase is an audit record of chips a player paid for, or was refunded.
+type Purchase struct {
+	PlayerID string    `json:"playerId"`
+	Kind     string    `json:"kind"`   // "entry", "re-entry", "rebuy", "add-on" or "refund"
+	Amount   int64     `json:"amount"` // to the prize pool and bounty, negative for a refund
+	Fee      int64     `json:"fee"`
+	Chips    int64     `json:"chips"`
+	At       time.Time `json:"at"`
+}
+
+type Tournament struct {
+	ID          string     `json:"id"`
+	Config      Config     `json:"config"`
+	Status      Status     `json:"status"`
+	Entries     []*Entry   `json:"entries"` // in registration order
+	Tables      []string   `json:"tables"`  // room IDs
+	Level       int        `json:"level"`   // index into Config.Levels
+	LevelEndsAt time.Time  `json:"levelEndsAt"`
+	StartedAt   time.Time  `json:"startedAt"`
+	HandForHand bool       `json:"handForHand"`
+	Purchases   []Purchase `json:"purchases"`
+	Deal        *Deal      `json:"deal,omitempty"`   // proposed to the final table
+	Agreed      *Deal      `json:"agreed,omitempty"` // made, leaving its remainder to play for
+	mu          sync.Mutex
+
+	// During hand-for-hand play, the tables still playing this round's
+	// hand and the stacks reported by those done with it.
+	pending     map[string]bool
+	roundStacks map[string]int64
+
+	tablesOpened int   // to number the next table
+	unclaimed    int64 // bounties of players knocked out by someone since gone, for the winner
+
+	unpaid     []payment // bounties and deals the wallet refused, to try again
+	payRetryAt time.Time
+}
+
+// payment is cash won that has not reached the player's wallet yet.
+type payment struct {
+	playerID string
+	kind     string // "bounty" or "deal"
+	amount   int64
+}
+
+func newTournament(id string, config Config) *Tournament {
+	return &Tournament{
+		ID:      id,
+		Config:  config,
+		Status:  StatusRegistering,
+		Entries: make([]*Entry, 0, config.Players),
+	}
+}
+
+// entry is the player's latest entry, the one still playing if any.
+func (t *Tournament) entry(playerID string) *Entry {
+	for i := len(t.Entries) - 1; i >= 0; i-- {
+		if t.Entries[i].PlayerID == playerID {
+			return t.Entries[i]
+		}
+	}
+	return nil
+}
+
+func (t *Tournament) entriesOf(playerID string) int {
+	n := 0
+	for _, e := range t.Entries {
+		if e.PlayerID == playerID {
+			n++
+		}
+	}
+	return n
+}
+
+// addEntry seats nobody yet. An entry made late finishes above everyone
+// already out, so they all drop a place.
+func (t *Tournament) addEntry(playerID, name string) *Entry {
+	for _, e := range t.Entries {
+		if e.Position != 0 {
+			e.Position++
+		}
+	}
+	e := &Entry{PlayerID: playerID, Name: name, Chips: t.Config.StartingStack, Bounty: t.Config.Bounty}
+	t.Entries = append(t.Entries, e)
+	return e
+}
+
+func (t *Tournament) audit(playerID, kind string, amount, fee, chips int64) {
+	t.Purchases = append(t.Purchases, Purchase{
+		PlayerID: playerID,
+		Kind:     kind,
+		Amount:   amount,
+		Fee:      fee,
+		Chips:    chips,
+		At:       time.Now(),
+	})
+}
+
+// owing reports whether any prize awarded, bounty or deal has still to be
+// paid.
+func (t *Tournament) owing() bool {
+	for _, e := range t.Entries {
+		if e.Prize != 0 && !e.paid {
+			return true
+		}
+	}
+	return len(t.unpaid) > 0
+}
+
+// remaining counts the entries still playing.
+func (t *Tournament) remaining() int {
+	n := 0
+	for _, e := range t.Entries {
+		if e.Position == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// PrizePool is every buy-in, rebuy and add-on paid so far, less bounties.
+func (t *Tournament) PrizePool() int64 {
+	buyIn := t.Config.BuyIn - t.Config.Bounty
+	pool := buyIn * int64(len(t.Entries))
+	for _, e := range t.Entries {
+		pool += buyIn * int64(e.Rebuys)
+		if e.AddOn {
+			pool += t.Config.AddOnCost
+		}
+	}
+	return pool
+}
+
+// lateRegistration reports whether a running tournament still takes
+// entries.
+func (t *Tournament) lateRegistration() bool {
+	return t.Status == StatusRunning && t.Level < t.Config.LateRegistration
+}
+
+func (t *Tournament) rebuyPeriod() bool {
+	return t.Status == StatusRunning && t.Level < t.Config.RebuyLevel
+}
+
+func (t *Tournament) canRebuy(e *Entry) bool {
+	return t.rebuyPeriod() && (t.Config.MaxRebuys == 0 || e.Rebuys < t.Config.MaxRebuys)
+}
+
+func (t *Tournament) onBreak() bool {
+	return t.Status == StatusRunning && t.Config.Levels[t.Level].Break
+}
+
+func (t *Tournament) addOnPeriod() bool {
+	return t.onBreak() && t.Config.AddOnChips > 0 && t.Level == t.Config.addOnLevel()
+}
+
+// buyingOpen reports whether the prize pool can still grow.
+func (t *Tournament) buyingOpen() bool {
+	return t.lateRegistration() || t.rebuyPeriod() ||
+		t.Status == StatusRunning && t.Config.AddOnChips > 0 && t.Level <= t.Config.addOnLevel()
+}
+
+// Prizes splits the prize pool by the payout structure, first place first.
+// Chips lost to rounding go to the winner.
+func (t *Tournament) Prizes() []int64 {
+	pool := t.PrizePool()
+	prizes := make([]int64, len(t.Config.Payouts))
+	var paid int64
+	for i, pct := range t.Config.Payouts {
+		prizes[i] = pool * int64(pct) / 100
+		paid += prizes[i]
+	}
+	prizes[0] += pool - paid
+	return prizes
+}
+
+func (t *Tournament) prizeFor(position int) int64 {
+	// A deal has shared out the places it covers, but for the remainder.
+	if t.Agreed != nil && position <= len(t.Agreed.Amounts) {
+		if position == 1 {
+			return t.Agreed.Remainder
+		}
+		return 0
+	}
+	prizes := t.Prizes()
+	if position < 1 || position > len(prizes) {
+		return 0
+	}
+	return prizes[position-1]
+}
+
+// recordHand updates stacks after a hand, or a hand-for-hand round, and
+// places everyone who busted. Players out in the same hand finish in the
+// order of the stacks they started it with, the bigger stack higher. The
+// last player standing is placed first. Returns who was placed, worst
+// first.
+//
+// A player who could still rebuy keeps their seat with no chips instead,
+// until they do or the rebuy period ends.
+func (t *Tournament) recordHand(stacks map[string]int64) []*Entry {
+	busted := make([]*Entry, 0)
+	for _, e := range t.Entries {
+		chips, seated := stacks[e.PlayerID]
+		if e.Position != 0 || !seated {
+			continue
+		}
+		if chips == 0 && !t.canRebuy(e) {
+			busted = append(busted, e)
+			continue
+		}
+		e.Chips = chips
+	}
+	sort.SliceStable(busted, func(i, j int) bool { return busted[i].Chips < busted[j].Chips })
+
+	place := t.remaining()
+	for _, e := range busted {
+		e.Chips = 0
+		e.Position = place
+		place--
+	}
+	if t.remaining() == 1 {
+		for _, e := range t.Entries {
+			if e.Position == 0 {
+				e.Position = 1
+				busted = append(busted, e)
+			}
+		}
+	}
+	t.awardPrizes()
+	return busted
+}
+
+// noteKnockouts remembers who won the chips of everyone a hand left with
+// none: the winners of every pot they were in. They collect the bounty
+// when the player is out, which is later for one who could still rebuy.
+func (t *Tournament) noteKnockouts(result game.HandResult, stacks map[string]int64) {
+	if t.Config.Bounty == 0 {
+		return
+	}
+	for _, e := range t.Entries {
+		if chips, seated := stacks[e.PlayerID]; e.Position != 0 || !seated || chips != 0 {
+			continue
+		}
+		won := make(map[string]bool)
+		for _, pot := range result.Pots {
+			if !containsID(pot.PlayerIDs, e.PlayerID) {
+				continue
+			}
+			for id := range pot.Winners {
+				won[id] = true
+			}
+		}
+		e.knockedOutBy = make([]string, 0, len(won))
+		for id := range won {
+			e.knockedOutBy = append(e.knockedOutBy, id)
+		}
+		sort.Strings(e.knockedOutBy)
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// claimBounty splits the bounty on a player who is out between those who
+// knocked them out, evenly when they split the pot. Part of it is paid at
+// once and the rest goes on their own heads. The winner collects their own
+// bounty along with any nobody was left to claim. Returns the cash won by
+// each player.
+func (t *Tournament) claimBounty(e *Entry) map[string]int64 {
+	cash := make(map[string]int64)
+	if e.Position == 1 {
+		if won := e.Bounty + t.unclaimed; won > 0 {
+			cash[e.PlayerID] = won
+			e.Bounties += won
+		}
+		e.Bounty = 0
+		t.unclaimed = 0
+		return cash
+	}
+
+	if e.Bounty == 0 {
+		return cash
+	}
+	// Whoever knocks out the last of the others has already won.
+	hunters := make([]*Entry, 0, len(e.knockedOutBy))
+	for _, id := range e.knockedOutBy {
+		if h := t.entry(id); h != nil && h.Position <= 1 {
+			hunters = append(hunters, h)
+		}
+	}
+	if len(hunters) == 0 {
+		t.unclaimed += e.Bounty
+		e.Bounty = 0
+		return cash
+	}
+
+	paid := e.Bounty * int64(t.Config.BountyCash) / 100
+	kept := e.Bounty - paid
+	n := int64(len(hunters))
+	for i, h := range hunters {
+		share, added := paid/n, kept/n
+		// The first of them gets what does not split evenly.
+		if i == 0 {
+			share += paid % n
+			added += kept % n
+		}
+		if share > 0 {
+			cash[h.PlayerID] = share
+		}
+		h.Bounties += share
+		h.Bounty += added
+	}
+	e.Bounty = 0
+	return cash
+}
+
+// awardPrizes gives the places in the money their prizes once nothing
+// more can be bought, so late entries, rebuys and add-ons are in the pool
+// they are paid from.
+func (t *Tournament) awardPrizes() {
+	if t.buyingOpen() && t.remaining() > 0 {
+		return
+	}
+	for _, e := range t.Entries {
+		if e.Position > 0 && e.Prize == 0 {
+			e.Prize = t.prizeFor(e.Position)
+		}
+	}
+}
+
+// tableCounts is how many players are left at each table.
+func (t *Tournament) tableCounts() map[string]int {
+	counts := make(map[string]int, len(t.Tables))
+	for _, id := range t.Tables {
+		counts[id] = 0
+	}
+	for _, e := range t.Entries {
+		if e.Position == 0 && e.RoomID != "" {
+			counts[e.RoomID]++
+		}
+	}
+	return counts
+}
+
+// tablesNeeded is the fewest tables that seat everyone still in.
+func (t *Tournament) tablesNeeded() int {
+	size := t.Config.tableSize()
+	return (t.remaining() + size - 1) / size
+}
+
+// smallestTable is the table other than except with the fewest players.
+func (t *Tournament) smallestTable(counts map[string]int, except string) string {
+	smallest := ""
+	for _, id := range t.Tables {
+		if id != except && (smallest == "" || counts[id] < counts[smallest]) {
+			smallest = id
+		}
+	}
+	return smallest
+}
+
+func (t *Tournament) seatedAt(roomID string) []*Entry {
+	seated := make([]*Entry, 0)
+	for _, e := range t.Entries {
+		if e.Position == 0 && e.RoomID == roomID {
+			seated = append(seated, e)
+		}
+	}
+	return seated
+}
+
+func (t *Tournament) removeTable(roomID string) {
+	for i, id := range t.Tables {
+		if id == roomID {
+			t.Tables = append(t.Tables[:i], t.Tables[i+1:]...)
+			return
+		}
+	}
+}
+
+// onTheBubble reports whether the next player out finishes just outside
+// the money, with the field spread over more than one table.
+func (t *Tournament) onTheBubble() bool {
+	return len(t.Tables) > 1 && t.remaining() == len(t.Config.Payouts)+1
+}
+
+// advanceLevel moves to the next blind level once the current one has run
+// its course. Reports whether it did.
+func (t *Tournament) advanceLevel(now time.Time) bool {
+	if t.Status != StatusRunning || now.Before(t.LevelEndsAt) || t.Level >= len(t.Config.Levels)-1 {
+		return false
+	}
+	t.Level++
+	t.LevelEndsAt = now.Add(time.Duration(t.Config.Levels[t.Level].Minutes) * time.Minute)
+	return true
+}
+
+// Standings lists players still in by stack, then everyone out by where
+// they finished.
+func (t *Tournament) Standings() []Entry {
+	standings := make([]Entry, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		standings = append(standings, *e)
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if (a.Position == 0) != (b.Position == 0) {
+			return a.Position == 0
+		}
+		if a.Position == 0 {
+			return a.Chips > b.Chips
+		}
+		return a.Position < b.Position
+	})
+	return standings
+}
+
+// playerIDs is everyone who has entered, once each.
+func (t *Tournament) playerIDs() []string {
+	ids := make([]string, 0, len(t.Entries))
+	seen := make(map[string]bool, len(t.Entries))
+	for _, e := range t.Entries {
+		if !seen[e.PlayerID] {
+			seen[e.PlayerID] = true
+			ids = append(ids, e.PlayerID)
+		}
+	}
+	return ids
+}
+
+// stakes is the level whose blinds are played, the one before a break
+// during it.
+func (t *Tournament) stakes() Level {
+	for i := t.Level; i > 0; i-- {
+		if !t.Config.Levels[i].Break {
+			return t.Config.Levels[i]
+		}
+	}
+	return t.Config.Levels[0]
+}
+
+func (t *Tournament) levelInfo() map[string]interface{} {
+	level := t.stakes()
+	return map[string]interface{}{
+		"tournamentId":     t.ID,
+		"level":            t.Level + 1,
+		"smallBlind":       level.SmallBlind,
+		"bigBlind":         level.BigBlind,
+		"ante":             level.Ante,
+		"endsAt":           t.LevelEndsAt.UnixMilli(),
+		"break":            t.onBreak(),
+		"lateRegistration": t.lateRegistration(),
+		"rebuys":           t.rebuyPeriod(),
+		"addOn":            t.addOnPeriod(),
+	}
+}
+
+func (t *Tournament) standingsInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"tournamentId": t.ID,
+		"status":       t.Status,
+		"remaining":    t.remaining(),
+		"tables":       len(t.Tables),
+		"handForHand":  t.HandForHand,
+		"prizePool":    t.PrizePool(),
+		"prizes":       t.Prizes(),
+		"deal":         t.Agreed,
+		"standings":    t.Standings(),
+	}
+}
+
+type Info struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Status        Status `json:"status"`
+	Registered    int    `json:"registered"`
+	Players       int    `json:"players"`
+	BuyIn         int64  `json:"buyIn"`
+	Fee           int64  `json:"fee"`
+	StartingStack int64  `json:"startingStack"`
+	PrizePool     int64  `json:"prizePool"`
+	Level         int    `json:"level"`
+	TableSize     int    `json:"tableSize"`
+	StartAt       int64  `json:"startAt,omitempty"`
+	LateEntry     bool   `json:"lateEntry"` // registration is still open after the start
+	Bounty        int64  `json:"bounty,omitempty"`
+}
+
+func (t *Tournament) ToInfo() Info {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Info{
+		ID:            t.ID,
+		Name:          t.Config.Name,
+		Status:        t.Status,
+		Registered:    len(t.Entries),
+		Players:       t.Config.Players,
+		BuyIn:         t.Config.BuyIn,
+		Fee:           t.Config.Fee,
+		StartingStack: t.Config.StartingStack,
+		PrizePool:     t.PrizePool(),
+		Level:         t.Level + 1,
+		TableSize:     t.Config.tableSize(),
+		StartAt:       t.Config.StartAt,
+		LateEntry:     t.lateRegistration(),
+		Bounty:        t.Config.Bounty,
+	}
+}
+
+// Details is everything a lobby shows about one tournament.
+func (t *Tournament) Details() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	details := t.standingsInfo()
+	details["config"] = t.Config
+	details["tables"] = t.tableCounts()
+	details["purchases"] = t.Purchases
+	if t.Status == StatusRunning {
+		details["level"] = t.levelInfo()
+	}
+	return details
+}
@@ -0,0 +1,621 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"texas-holdem-server/internal/game"
+	"texas-holdem-server/internal/room"
+)
+
+func newRunningTournament(players int) *Tournament {
+	config := DefaultSitAndGo()
+	config.Players = players
+	t := newTournament("t1", config)
+	for i := 0; i < players; i++ {
+		id := string(rune('a' + i))
+		t.Entries = append(t.Entries, &Entry{PlayerID: id, Name: id, Chips: config.StartingStack, RoomID: "r1"})
+	}
+	t.Status = StatusRunning
+	return t
+}
+
+func TestValidate(t *testing.T) {
+	if err := DefaultSitAndGo().Validate(); err != nil {
+		t.Errorf("Expected the default Sit & Go to be valid, got %v", err)
+	}
+
+	config := DefaultSitAndGo()
+	config.Payouts = []int{50, 30}
+	if err := config.Validate(); err == nil {
+		t.Errorf("Expected payouts short of 100%% to be rejected")
+	}
+
+	config = DefaultSitAndGo()
+	config.Players = 2
+	config.Payouts = []int{50, 30, 20}
+	if err := config.Validate(); err == nil {
+		t.Errorf("Expected more paid places than players to be rejected")
+	}
+}
+
+func TestPrizesGiveRoundingToTheWinner(t *testing.T) {
+	config := DefaultSitAndGo()
+	config.Players = 3
+	config.BuyIn = 333
+	config.Payouts = []int{50, 30, 20}
+	tr := newTournament("t1", config)
+	for _, id := range []string{"a", "b", "c"} {
+		tr.Entries = append(tr.Entries, &Entry{PlayerID: id})
+	}
+
+	prizes := tr.Prizes()
+	expected := []int64{501, 299, 199}
+	for i := range expected {
+		if prizes[i] != expected[i] {
+			t.Errorf("Expected prize %d to be %d, got %d", i+1, expected[i], prizes[i])
+		}
+	}
+}
+
+func TestRecordHandPlacesBustedPlayers(t *testing.T) {
+	tr := newRunningTournament(4)
+	tr.Entries[0].Chips = 1000
+	tr.Entries[1].Chips = 500
+
+	// a and b bust in the same hand; a started it with more chips.
+	placed := tr.recordHand(map[string]int64{"a": 0, "b": 0, "c": 3500, "d": 1000})
+	if len(placed) != 2 {
+		t.Fatalf("Expected 2 players placed, got %d", len(placed))
+	}
+	if placed[0].PlayerID != "b" || placed[0].Position != 4 {
+		t.Errorf("Expected b to finish 4th, got %s in %d", placed[0].PlayerID, placed[0].Position)
+	}
+	if placed[1].PlayerID != "a" || placed[1].Position != 3 {
+		t.Errorf("Expected a to finish 3rd, got %s in %d", placed[1].PlayerID, placed[1].Position)
+	}
+	if tr.remaining() != 2 {
+		t.Errorf("Expected 2 players left, got %d", tr.remaining())
+	}
+
+	placed = tr.recordHand(map[string]int64{"c": 4500, "d": 0})
+	if len(placed) != 2 || placed[1].PlayerID != "c" || placed[1].Position != 1 {
+		t.Fatalf("Expected c to win once d busts, got %+v", placed)
+	}
+	prizes := tr.Prizes()
+	if placed[1].Prize != prizes[0] || placed[0].Prize != prizes[1] {
+		t.Errorf("Expected prizes %v, got %d and %d", prizes[:2], placed[1].Prize, placed[0].Prize)
+	}
+
+	standings := tr.Standings()
+	for i, e := range standings {
+		if e.Position != i+1 {
+			t.Errorf("Expected standings in finishing order, got %s in %d at %d", e.PlayerID, e.Position, i+1)
+		}
+	}
+}
+
+func TestTablesAndBubble(t *testing.T) {
+	tr := newRunningTournament(7)
+	tr.Config.TableSize = 3
+	tr.Config.Payouts = []int{50, 30, 20}
+	tr.Tables = []string{"r1", "r2", "r3"}
+	for _, e := range tr.Entries[3:5] {
+		e.RoomID = "r2"
+	}
+	for _, e := range tr.Entries[5:] {
+		e.RoomID = "r3"
+	}
+
+	counts := tr.tableCounts()
+	if counts["r1"] != 3 || counts["r2"] != 2 || counts["r3"] != 2 {
+		t.Errorf("Expected 3, 2 and 2 players, got %v", counts)
+	}
+	if tr.smallestTable(counts, "") != "r2" || tr.smallestTable(counts, "r2") != "r3" {
+		t.Errorf("Expected r2, then r3, to be the smallest table")
+	}
+
+	tr.recordHand(map[string]int64{"a": 0, "b": 0, "c": 4500})
+	if tr.tablesNeeded() != 2 || tr.onTheBubble() {
+		t.Errorf("Expected 2 tables needed and no bubble with 5 left")
+	}
+
+	tr.recordHand(map[string]int64{"d": 3000, "e": 0})
+	if tr.tablesNeeded() != 2 || !tr.onTheBubble() {
+		t.Errorf("Expected the bubble with 4 left and 3 paid")
+	}
+
+	tr.recordHand(map[string]int64{"d": 0, "f": 4500})
+	if tr.tablesNeeded() != 1 || tr.onTheBubble() {
+		t.Errorf("Expected one table and the bubble burst with 3 left")
+	}
+}
+
+func TestAdvanceLevel(t *testing.T) {
+	tr := newRunningTournament(2)
+	start := time.Now()
+	tr.LevelEndsAt = start.Add(time.Minute)
+
+	if tr.advanceLevel(start) {
+		t.Errorf("Expected the level to run until it ends")
+	}
+	if !tr.advanceLevel(start.Add(time.Minute)) || tr.Level != 1 {
+		t.Errorf("Expected level 2, got %d", tr.Level+1)
+	}
+
+	tr.Level = len(tr.Config.Levels) - 1
+	tr.LevelEndsAt = start
+	if tr.advanceLevel(start.Add(time.Hour)) {
+		t.Errorf("Expected the last level to last until the end")
+	}
+}
+
+type testWallet map[string]int64
+
+func (w testWallet) AddChips(userID string, amount int64, reason string) error {
+	w[userID] += amount
+	return nil
+}
+
+func (w testWallet) DeductChips(userID string, amount int64, reason string) error {
+	w[userID] -= amount
+	return nil
+}
+
+// downWallet refuses every payment while down.
+type downWallet struct {
+	testWallet
+	down bool
+}
+
+func (w *downWallet) AddChips(userID string, amount int64, reason string) error {
+	if w.down {
+		return fmt.Errorf("wallet unavailable")
+	}
+	return w.testWallet.AddChips(userID, amount, reason)
+}
+
+func TestArrangeTablesBreaksAndBalances(t *testing.T) {
+	rooms := room.NewManager(nil)
+	s := NewService(rooms, testWallet{})
+	defer s.Stop()
+
+	config := DefaultSitAndGo()
+	config.Players = 12
+	config.TableSize = 4
+	tr, _ := s.Create(config)
+
+	// Tables of 4, 4 and 1 that are never started, so all are idle.
+	sizes := []int{4, 4, 1}
+	n := 0
+	for i, size := range sizes {
+		r, err := s.openTable(tr, i+1)
+		if err != nil {
+			t.Fatalf("Expected a table, got %v", err)
+		}
+		tr.Tables = append(tr.Tables, r.ID)
+		for j := 0; j < size; j++ {
+			id := fmt.Sprintf("p%d", n)
+			n++
+			r.AddPlayer(id, id, 1500)
+			tr.Entries = append(tr.Entries, &Entry{PlayerID: id, Name: id, Chips: 1500, RoomID: r.ID})
+		}
+	}
+	tr.Status = StatusRunning
+	broken := tr.Tables[2]
+
+	events := s.arrangeTables(tr, append([]string(nil), tr.Tables...))
+	if len(tr.Tables) != 3 {
+		t.Fatalf("Expected 9 players to keep 3 tables of 4, got %d tables", len(tr.Tables))
+	}
+	counts := tr.tableCounts()
+	for id, count := range counts {
+		if count != 3 {
+			t.Errorf("Expected 3 players at every table, got %d at %s", count, id)
+		}
+		if r := rooms.GetRoom(id); r.GetPlayerCount() != count {
+			t.Errorf("Expected the room to seat %d, got %d", count, r.GetPlayerCount())
+		}
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected 2 players moved, got %d", len(events))
+	}
+
+	// Two more out leaves 7, which fit at two tables; the smallest one breaks.
+	for _, e := range tr.seatedAt(broken)[:2] {
+		rooms.GetRoom(broken).RemovePlayer(e.PlayerID)
+		e.Position = n
+		n--
+	}
+	s.arrangeTables(tr, append([]string(nil), tr.Tables...))
+	if len(tr.Tables) != 2 || rooms.GetRoom(broken) != nil {
+		t.Fatalf("Expected table %s to break, got tables %v", broken, tr.Tables)
+	}
+	counts = tr.tableCounts()
+	for id, count := range counts {
+		if count < 3 || count > 4 {
+			t.Errorf("Expected 3 or 4 players at every table, got %d at %s", count, id)
+		}
+	}
+	for _, e := range tr.Entries {
+		if e.Position == 0 && !rooms.GetRoom(e.RoomID).HasPlayer(e.PlayerID) {
+			t.Errorf("Expected %s to be seated at %s", e.PlayerID, e.RoomID)
+		}
+	}
+}
+
+func TestLateEntryAndDeferredPrizes(t *testing.T) {
+	tr := newRunningTournament(3)
+	tr.Config.Players = 4
+	tr.Config.ReEntries = 1
+	tr.Config.LateRegistration = 2
+
+	tr.recordHand(map[string]int64{"a": 0, "b": 3000})
+	a := tr.entry("a")
+	if a.Position != 3 || a.Prize != 0 {
+		t.Errorf("Expected a 3rd with the prize held back, got %d and %d", a.Position, a.Prize)
+	}
+
+	tr.addEntry("a", "a")
+	if a.Position != 4 || tr.entry("a") == a || tr.entriesOf("a") != 2 {
+		t.Errorf("Expected a's re-entry to push their first entry down to 4th, got %d", a.Position)
+	}
+	if tr.PrizePool() != 4*tr.Config.BuyIn {
+		t.Errorf("Expected 4 buy-ins in the pool, got %d", tr.PrizePool())
+	}
+
+	// Late registration is over, so the pool is final and prizes are paid.
+	tr.Level = 2
+	tr.recordHand(map[string]int64{"b": 0, "c": 3000, "a": 1500})
+	placed := tr.recordHand(map[string]int64{"c": 0, "a": 4500})
+	if len(placed) != 2 || placed[1].PlayerID != "a" || placed[1].Position != 1 {
+		t.Fatalf("Expected a's re-entry to win, got %+v", placed)
+	}
+	prizes := tr.Prizes()
+	if placed[1].Prize != prizes[0] || placed[0].Prize != prizes[1] || tr.entry("b").Prize != 0 {
+		t.Errorf("Expected prizes %v for 1st and 2nd only", prizes)
+	}
+}
+
+func TestRebuyPeriodKeepsBustedPlayersSeated(t *testing.T) {
+	tr := newRunningTournament(3)
+	tr.Config.RebuyLevel = 1
+	tr.Config.MaxRebuys = 1
+
+	placed := tr.recordHand(map[string]int64{"a": 0, "b": 3000})
+	if len(placed) != 0 || tr.entry("a").Chips != 0 || tr.remaining() != 3 {
+		t.Errorf("Expected a to wait on a rebuy, got %d placed", len(placed))
+	}
+
+	tr.entry("a").Rebuys = 1
+	placed = tr.recordHand(map[string]int64{"a": 0, "b": 3000})
+	if len(placed) != 1 || placed[0].Position != 3 {
+		t.Errorf("Expected a out in 3rd with no rebuys left, got %+v", placed)
+	}
+}
+
+func TestRebuyAndAddOn(t *testing.T) {
+	rooms := room.NewManager(nil)
+	wallet := testWallet{}
+	s := NewService(rooms, wallet)
+	defer s.Stop()
+
+	config := DefaultSitAndGo()
+	config.Players = 2
+	config.RebuyLevel = 1
+	config.AddOnCost = 500
+	config.AddOnChips = 2000
+	config.Levels = append(config.Levels[:1], Level{Break: true, Minutes: 5}, config.Levels[1])
+	tr, err := s.Create(config)
+	if err != nil {
+		t.Fatalf("Expected a valid config, got %v", err)
+	}
+
+	r, _ := s.openTable(tr, 1)
+	tr.Tables = []string{r.ID}
+	for _, id := range []string{"a", "b"} {
+		r.AddPlayer(id, id, 1500)
+		tr.Entries = append(tr.Entries, &Entry{PlayerID: id, Name: id, Chips: 1500, RoomID: r.ID})
+	}
+	tr.Status = StatusRunning
+
+	if err := s.AddOn(tr.ID, "a"); err == nil {
+		t.Errorf("Expected no add-on before the break")
+	}
+	if err := s.Rebuy(tr.ID, "a"); err != nil {
+		t.Fatalf("Expected a rebuy at the starting stack, got %v", err)
+	}
+	if err := s.Rebuy(tr.ID, "a"); err == nil {
+		t.Errorf("Expected no rebuy above the starting stack")
+	}
+	if wallet["a"] != -(config.BuyIn + config.Fee) {
+		t.Errorf("Expected the rebuy to cost %d, got %d", config.BuyIn+config.Fee, -wallet["a"])
+	}
+
+	tr.Level = 1
+	if err := s.Rebuy(tr.ID, "b"); err == nil {
+		t.Errorf("Expected no rebuy after the rebuy period")
+	}
+	if err := s.AddOn(tr.ID, "a"); err != nil {
+		t.Fatalf("Expected the add-on at the break, got %v", err)
+	}
+	if err := s.AddOn(tr.ID, "a"); err == nil {
+		t.Errorf("Expected one add-on per entry")
+	}
+
+	if a := tr.entry("a"); a.Chips != 5000 || a.Rebuys != 1 || !a.AddOn {
+		t.Errorf("Expected a on 5000 after a rebuy and the add-on, got %+v", a)
+	}
+	if info := r.ToInfo(); info.Players[0].Chips != 5000 {
+		t.Errorf("Expected 5000 chips at the table, got %d", info.Players[0].Chips)
+	}
+	if pool := tr.PrizePool(); pool != 3*config.BuyIn+config.AddOnCost {
+		t.Errorf("Expected a prize pool of %d, got %d", 3*config.BuyIn+config.AddOnCost, pool)
+	}
+	if len(tr.Purchases) != 2 || tr.Purchases[0].Kind != "rebuy" || tr.Purchases[1].Kind != "add-on" {
+		t.Errorf("Expected a rebuy and an add-on audited, got %+v", tr.Purchases)
+	}
+}
+
+func TestProgressiveBounties(t *testing.T) {
+	tr := newTournament("t1", DefaultSitAndGo())
+	tr.Config.Players = 3
+	tr.Config.Bounty = 400
+	tr.Config.BountyCash = 50
+	for _, id := range []string{"a", "b", "c"} {
+		tr.addEntry(id, id)
+	}
+	tr.Status = StatusRunning
+	if tr.PrizePool() != 3*600 {
+		t.Errorf("Expected 600 of every buy-in in the prize pool, got %d", tr.PrizePool())
+	}
+
+	// b and c split the pot a was all in for.
+	result := game.HandResult{Pots: []game.PotResult{
+		{PlayerIDs: []string{"a", "b", "c"}, Winners: map[string]int64{"b": 2250, "c": 2250}},
+	}}
+	stacks := map[string]int64{"a": 0, "b": 2250, "c": 2250}
+	tr.noteKnockouts(result, stacks)
+	placed := tr.recordHand(stacks)
+	cash := tr.claimBounty(placed[0])
+	if cash["b"] != 100 || cash["c"] != 100 {
+		t.Errorf("Expected b and c to be paid 100 each, got %v", cash)
+	}
+	if b := tr.entry("b"); b.Bounty != 500 || b.Bounties != 100 {
+		t.Errorf("Expected b's bounty to grow to 500, got %d", b.Bounty)
+	}
+
+	// c takes b's 500 bounty and wins, collecting their own as well.
+	result = game.HandResult{Pots: []game.PotResult{
+		{PlayerIDs: []string{"b", "c"}, Winners: map[string]int64{"c": 4500}},
+	}}
+	stacks = map[string]int64{"b": 0, "c": 4500}
+	tr.noteKnockouts(result, stacks)
+	placed = tr.recordHand(stacks)
+	if len(placed) != 2 {
+		t.Fatalf("Expected b out and c the winner, got %+v", placed)
+	}
+	tr.claimBounty(placed[0])
+	cash = tr.claimBounty(placed[1])
+	if c := tr.entry("c"); cash["c"] != 750 || c.Bounties != 100+250+750 || c.Bounty != 0 {
+		t.Errorf("Expected c to collect their 750 bounty, got %v and %d in all", cash, c.Bounties)
+	}
+
+	var bounties int64
+	for _, e := range tr.Entries {
+		bounties += e.Bounties
+	}
+	if bounties != 3*400 {
+		t.Errorf("Expected every bounty paid out, got %d", bounties)
+	}
+}
+
+func TestICM(t *testing.T) {
+	tests := []struct {
+		stacks, payouts, expected []int64
+	}{
+		{[]int64{3000, 1000}, []int64{700, 300}, []int64{600, 400}},
+		{[]int64{1000, 1000, 1000}, []int64{500, 300, 200}, []int64{334, 333, 333}},
+		{[]int64{5000, 3000, 2000}, []int64{500, 300, 200}, []int64{384, 327, 289}},
+		// Only the places paid count.
+		{[]int64{5000, 3000, 2000}, []int64{1000}, []int64{500, 300, 200}},
+	}
+	for _, tt := range tests {
+		amounts, err := ICM(tt.stacks, tt.payouts)
+		if err != nil {
+			t.Fatalf("Expected ICM of %v, got %v", tt.stacks, err)
+		}
+		for i := range tt.expected {
+			if amounts[i] != tt.expected[i] {
+				t.Errorf("Expected ICM of %v over %v to be %v, got %v", tt.stacks, tt.payouts, tt.expected, amounts)
+				break
+			}
+		}
+	}
+
+	if _, err := ICM([]int64{1000, 0}, []int64{100}); err == nil {
+		t.Errorf("Expected an empty stack to be rejected")
+	}
+}
+
+func TestChipChop(t *testing.T) {
+	amounts, err := ChipChop([]int64{6000, 3000, 1000}, []int64{500, 300, 200})
+	if err != nil {
+		t.Fatalf("Expected a chip chop, got %v", err)
+	}
+	expected := []int64{440, 320, 240}
+	for i := range expected {
+		if amounts[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, amounts)
+			break
+		}
+	}
+}
+
+func TestChipChopWithARemainderOutOfFirstPlace(t *testing.T) {
+	// 100 and 50 heads-up, with 90 of first place kept back.
+	amounts, err := ChipChop([]int64{1000, 1}, []int64{10, 50})
+	if err != nil {
+		t.Fatalf("Expected a chip chop, got %v", err)
+	}
+	if amounts[0] != 50 || amounts[1] != 10 {
+		t.Errorf("Expected [50 10], got %v", amounts)
+	}
+}
+
+func newFinalTable(t *testing.T, s *Service, rooms *room.Manager, stacks map[string]int64) *Tournament {
+	config := DefaultSitAndGo()
+	config.Players = 4
+	config.Payouts = []int{50, 30, 20}
+	tr, _ := s.Create(config)
+	r, err := s.openTable(tr, 1)
+	if err != nil {
+		t.Fatalf("Expected a table, got %v", err)
+	}
+	tr.Tables = []string{r.ID}
+	for _, id := range []string{"a", "b", "c"} {
+		r.AddPlayer(id, id, stacks[id])
+		tr.Entries = append(tr.Entries, &Entry{PlayerID: id, Name: id, Chips: stacks[id], RoomID: r.ID})
+	}
+	tr.Entries = append(tr.Entries, &Entry{PlayerID: "d", Name: "d", Position: 4})
+	tr.Status = StatusRunning
+	return tr
+}
+
+func TestDealEndsTheTournament(t *testing.T) {
+	rooms := room.NewManager(nil)
+	wallet := testWallet{}
+	s := NewService(rooms, wallet)
+	defer s.Stop()
+	tr := newFinalTable(t, s, rooms, map[string]int64{"a": 3000, "b": 2000, "c": 1000})
+
+	if err := s.ProposeDeal(tr.ID, "a", "icm", 0); err != nil {
+		t.Fatalf("Expected a deal proposed, got %v", err)
+	}
+	if err := s.ProposeDeal(tr.ID, "b", "chip", 0); err == nil {
+		t.Errorf("Expected one deal at a time")
+	}
+	s.AcceptDeal(tr.ID, "b")
+	if tr.Status != StatusRunning {
+		t.Fatalf("Expected the deal to wait for c")
+	}
+	s.AcceptDeal(tr.ID, "c")
+
+	if tr.Status != StatusFinished {
+		t.Fatalf("Expected the deal to end the tournament, got %s", tr.Status)
+	}
+	var paid int64
+	for _, id := range []string{"a", "b", "c"} {
+		paid += wallet[id]
+	}
+	if paid != tr.PrizePool() {
+		t.Errorf("Expected the whole prize pool of %d shared out, got %d", tr.PrizePool(), paid)
+	}
+	if wallet["a"] <= wallet["b"] || wallet["b"] <= wallet["c"] {
+		t.Errorf("Expected bigger stacks to get more, got %v", wallet)
+	}
+	for i, e := range tr.Standings() {
+		if e.Position != i+1 {
+			t.Errorf("Expected the players placed by chips, got %s in %d", e.PlayerID, e.Position)
+		}
+	}
+}
+
+func TestDealWithARemainder(t *testing.T) {
+	rooms := room.NewManager(nil)
+	wallet := testWallet{}
+	s := NewService(rooms, wallet)
+	defer s.Stop()
+	tr := newFinalTable(t, s, rooms, map[string]int64{"a": 3000, "b": 2000, "c": 1000})
+
+	if err := s.ProposeDeal(tr.ID, "a", "chip", 10000); err == nil {
+		t.Errorf("Expected a remainder above the first prize to be rejected")
+	}
+	s.ProposeDeal(tr.ID, "a", "chip", 400)
+	s.RejectDeal(tr.ID, "c")
+	if tr.Deal != nil {
+		t.Fatalf("Expected the deal to be off")
+	}
+
+	s.ProposeDeal(tr.ID, "a", "chip", 400)
+	s.AcceptDeal(tr.ID, "b")
+	s.AcceptDeal(tr.ID, "c")
+	if tr.Status != StatusRunning || tr.Agreed == nil {
+		t.Fatalf("Expected play to go on for the remainder")
+	}
+
+	tr.recordHand(map[string]int64{"a": 0, "b": 5000, "c": 1000})
+	tr.recordHand(map[string]int64{"b": 6000, "c": 0})
+	if b := tr.entry("b"); b.Position != 1 || b.Prize != 400 {
+		t.Errorf("Expected b to win the 400 remainder, got %d in %d", b.Prize, b.Position)
+	}
+	var total int64
+	for _, e := range tr.Entries {
+		total += e.Deal + e.Prize
+	}
+	if total != tr.PrizePool() {
+		t.Errorf("Expected deals and prizes to add up to %d, got %d", tr.PrizePool(), total)
+	}
+}
+
+func TestRefusedPaymentsAreRetried(t *testing.T) {
+	rooms := room.NewManager(nil)
+	wallet := &downWallet{testWallet: testWallet{}, down: true}
+	s := NewService(rooms, wallet)
+	defer s.Stop()
+	tr := newFinalTable(t, s, rooms, map[string]int64{"a": 3000, "b": 2000, "c": 1000})
+	tr.entry("d").Prize = 100
+
+	s.ProposeDeal(tr.ID, "a", "chip", 0)
+	s.AcceptDeal(tr.ID, "b")
+	s.AcceptDeal(tr.ID, "c")
+	if tr.Status != StatusFinished || len(wallet.testWallet) != 0 {
+		t.Fatalf("Expected the deal made with nothing paid, got %s and %v", tr.Status, wallet.testWallet)
+	}
+	if !tr.owing() {
+		t.Fatalf("Expected the refused payments to be kept")
+	}
+
+	wallet.down = false
+	now := time.Now()
+	tr.mu.Lock()
+	tr.payRetryAt = now
+	tr.mu.Unlock()
+	s.tickClock(now)
+	var paid int64
+	for _, amount := range wallet.testWallet {
+		paid += amount
+	}
+	if paid != tr.PrizePool()+100 || tr.owing() {
+		t.Errorf("Expected the deal and d's prize paid once the wallet is back, got %v", wallet.testWallet)
+	}
+}
+
+func TestShutdownSettlesTournaments(t *testing.T) {
+	rooms := room.NewManager(nil)
+	wallet := testWallet{}
+	s := NewService(rooms, wallet)
+	defer s.Stop()
+	running := newFinalTable(t, s, rooms, map[string]int64{"a": 3000, "b": 2000, "c": 1000})
+	registering, _ := s.Create(DefaultSitAndGo())
+	s.Register(registering.ID, "e", "e")
+
+	s.Shutdown()
+	if registering.Status != StatusCancelled || wallet["e"] != 0 {
+		t.Errorf("Expected the registration refunded, got %s and %d", registering.Status, wallet["e"])
+	}
+	if running.Status != StatusFinished || rooms.GetRoom(running.entry("a").RoomID) != nil {
+		t.Fatalf("Expected the running tournament ended and its tables closed, got %s", running.Status)
+	}
+	var paid int64
+	for _, id := range []string{"a", "b", "c"} {
+		paid += wallet[id]
+	}
+	if paid != running.PrizePool() || wallet["a"] <= wallet["b"] || wallet["b"] <= wallet["c"] {
+		t.Errorf("Expected the prize pool of %d chopped by chips, got %v", running.PrizePool(), wallet)
+	}
+	if a := running.entry("a"); a.Position != 1 {
+		t.Errorf("Expected the chip leader placed first, got %d", a.Position)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"texas-holdem-server/internal/room"
+	"texas-holdem-server/internal/tournament"
 )
 
 var upgrader = websocket.Upgrader{
@@ -20,12 +21,86 @@ var upgrader = websocket.Upgrader{
 type Handler struct {
 	hub         *Hub
 	roomManager *room.Manager
+	tournaments *tournament.Service
 }
 
-func NewHandler(hub *Hub, roomManager *room.Manager) *Handler {
-	return &Handler{
+func NewHandler(hub *Hub, roomManager *room.Manager, tournaments *tournament.Service) *Handler {
+	h := &Handler{
 		hub:         hub,
 		roomManager: roomManager,
+		tournaments: tournaments,
+	}
+	roomManager.SetEventHandler(h.handleRoomEvent)
+	tournaments.SetEventHandler(h.handleTournamentEvent)
+	return h
+}
+
+func (h *Handler) handleRoomEvent(roomID, eventType string, data interface{}) {
+	if eventType == "game_state" {
+		h.sendGameState(roomID)
+		return
+	}
+	h.hub.SendToRoom(roomID, NewMessage(eventType, data))
+}
+
+// sendGameState gives every client in the room its own view of the game,
+// so nobody is sent cards they are not allowed to see. Whoever is to act
+// also gets their options privately.
+func (h *Handler) sendGameState(roomID string) {
+	r := h.roomManager.GetRoom(roomID)
+	if r == nil {
+		return
+	}
+	for _, client := range h.hub.GetRoomClients(roomID) {
+		msg := NewMessage("game_state", r.GameStateFor(client.PlayerID))
+		msg.RoomID = roomID
+		h.hub.SendToClient(client.ID, msg)
+
+		if turn := r.TurnFor(client.PlayerID); turn != nil {
+			msg := NewMessage("your_turn", turn)
+			msg.RoomID = roomID
+			h.hub.SendToClient(client.ID, msg)
+		}
+	}
+}
+
+// handleTournamentEvent sends tournament messages to the players they are
+// for, wherever they are, and brings a player sent to a table into its
+// room.
+func (h *Handler) handleTournamentEvent(playerIDs []string, eventType string, data interface{}) {
+	clients := h.hub.GetPlayerClients(playerIDs)
+	for _, client := range clients {
+		client.Send(NewMessage(eventType, data))
+	}
+
+	seat, ok := data.(tournament.Seat)
+	if !ok {
+		return
+	}
+	r := h.roomManager.GetRoom(seat.RoomID)
+	if r == nil {
+		return
+	}
+	for _, playerID := range playerIDs {
+		if seat.FromRoomID != "" {
+			h.hub.SendToRoom(seat.FromRoomID, NewMessage("player_left", map[string]string{
+				"playerId": playerID,
+			}))
+		}
+		h.hub.SendToRoom(seat.RoomID, NewMessage("player_joined", map[string]interface{}{
+			"playerId": playerID,
+		}))
+	}
+	for _, client := range clients {
+		if client.RoomID != "" && client.RoomID != seat.RoomID {
+			h.hub.LeaveRoom(client.RoomID, client)
+		}
+		h.hub.JoinRoom(seat.RoomID, client)
+		client.Send(NewMessage("room_joined", r.ToInfo()))
+		client.Send(NewMessage("game_state", r.GameStateFor(client.PlayerID)))
+		if turn := r.TurnFor(client.PlayerID); turn != nil {
+			client.Send(NewMessage("your_turn", turn))
+		}
 	}
 }
 
@@ -96,6 +171,51 @@ func (h *Handler) handleMessage(client *Client, msg *Message) {
 	case "buy_in":
 		h.handleBuyIn(client, msg)
 
+	case "use_time_bank":
+		h.handleUseTimeBank(client, msg)
+
+	case "client_seed":
+		h.handleClientSeed(client, msg)
+
+	case "run_it":
+		h.handleRunIt(client, msg)
+
+	case "straddle":
+		h.handleStraddle(client, msg)
+
+	case "show_cards":
+		h.handleShowCards(client, msg)
+
+	case "always_show":
+		h.handleAlwaysShow(client, msg)
+
+	case "discard":
+		h.handleDiscard(client, msg)
+
+	case "create_tournament":
+		h.handleCreateTournament(client, msg)
+
+	case "register_tournament":
+		h.handleRegisterTournament(client, msg)
+
+	case "unregister_tournament":
+		h.handleUnregisterTournament(client, msg)
+
+	case "rebuy_tournament":
+		h.handleTournamentPurchase(client, msg, "tournament_rebuy", h.tournaments.Rebuy)
+
+	case "add_on_tournament":
+		h.handleTournamentPurchase(client, msg, "tournament_add_on", h.tournaments.AddOn)
+
+	case "propose_deal":
+		h.handleProposeDeal(client, msg)
+
+	case "accept_deal":
+		h.handleAnswerDeal(client, msg, h.tournaments.AcceptDeal)
+
+	case "reject_deal":
+		h.handleAnswerDeal(client, msg, h.tournaments.RejectDeal)
+
 	default:
 		log.Printf("Unknown message type: %s", msg.Type)
 	}
@@ -160,6 +280,11 @@ func (h *Handler) handleJoinRoom(client *Client, msg *Message) {
 	r := h.roomManager.GetRoom(data.RoomID)
 	if r != nil {
 		client.Send(NewMessage("room_joined", r.ToInfo()))
+		client.Send(NewMessage("game_state", r.GameStateFor(client.PlayerID)))
+		// A player coming back mid-hand may already be on the clock.
+		if turn := r.TurnFor(client.PlayerID); turn != nil {
+			client.Send(NewMessage("your_turn", turn))
+		}
 
 		h.hub.SendToRoom(data.RoomID, NewMessage("player_joined", map[string]interface{}{
 			"playerId": client.PlayerID,
@@ -201,6 +326,7 @@ func (h *Handler) handleQuickMatch(client *Client, msg *Message) {
 	r := h.roomManager.GetRoom(roomID)
 	if r != nil {
 		client.Send(NewMessage("room_joined", r.ToInfo()))
+		client.Send(NewMessage("game_state", r.GameStateFor(client.PlayerID)))
 	}
 }
 
@@ -230,15 +356,140 @@ func (h *Handler) handlePlayerAction(client *Client, msg *Message) {
 		return
 	}
 
-	r := h.roomManager.GetRoom(client.RoomID)
-	if r != nil {
-		h.hub.SendToRoom(client.RoomID, NewMessage("player_action", map[string]interface{}{
-			"playerId": client.PlayerID,
-			"action":   data.Action,
-			"amount":   data.Amount,
-		}))
+	h.sendGameState(client.RoomID)
+}
+
+func (h *Handler) handleUseTimeBank(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	if err := h.roomManager.UseTimeBank(client.RoomID, client.PlayerID); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+	}
+}
+
+func (h *Handler) handleClientSeed(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	var data struct {
+		Seed string `json:"seed"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.roomManager.SetClientSeed(client.RoomID, client.PlayerID, data.Seed); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+	}
+}
+
+func (h *Handler) handleRunIt(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	var data struct {
+		Runs int `json:"runs"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.roomManager.RespondRunIt(client.RoomID, client.PlayerID, data.Runs); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+
+	h.hub.SendToRoom(client.RoomID, NewMessage("run_it_response", map[string]interface{}{
+		"playerId": client.PlayerID,
+		"runs":     data.Runs,
+	}))
+	h.sendGameState(client.RoomID)
+}
+
+func (h *Handler) handleStraddle(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	var data struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.roomManager.SetStraddle(client.RoomID, client.PlayerID, data.Enabled); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+	}
+}
+
+func (h *Handler) handleShowCards(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	var data struct {
+		Cards []int `json:"cards"` // indices into the player's hole cards
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.roomManager.ShowCards(client.RoomID, client.PlayerID, data.Cards); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+	}
+}
+
+func (h *Handler) handleDiscard(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	var data struct {
+		Card int `json:"card"` // index into the player's hole cards
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
 
-		h.hub.SendToRoom(client.RoomID, NewMessage("game_state", r.GetGameState()))
+	if err := h.roomManager.Discard(client.RoomID, client.PlayerID, data.Card); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+	h.sendGameState(client.RoomID)
+}
+
+func (h *Handler) handleAlwaysShow(client *Client, msg *Message) {
+	if client.RoomID == "" {
+		client.Send(NewMessage("error", map[string]string{"message": "not in a room"}))
+		return
+	}
+
+	var data struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.roomManager.SetAlwaysShow(client.RoomID, client.PlayerID, data.Enabled); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
 	}
 }
 
@@ -295,5 +546,110 @@ func (h *Handler) handleBuyIn(client *Client, msg *Message) {
 		return
 	}
 
-	h.roomManager.BuyIn(client.RoomID, client.PlayerID, data.Amount)
+	if err := h.roomManager.BuyIn(client.RoomID, client.PlayerID, data.Amount); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+	h.sendGameState(client.RoomID)
+}
+
+func (h *Handler) handleCreateTournament(client *Client, msg *Message) {
+	config := tournament.DefaultSitAndGo()
+	if err := msg.ParseData(&config); err != nil {
+		config = tournament.DefaultSitAndGo()
+	}
+
+	t, err := h.tournaments.Create(config)
+	if err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+
+	client.Send(NewMessage("tournament_created", t.ToInfo()))
+}
+
+func (h *Handler) handleRegisterTournament(client *Client, msg *Message) {
+	var data struct {
+		TournamentID string `json:"tournamentId"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.tournaments.Register(data.TournamentID, client.PlayerID, client.Name); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+	client.Send(NewMessage("tournament_registered", map[string]string{"tournamentId": data.TournamentID}))
+}
+
+func (h *Handler) handleUnregisterTournament(client *Client, msg *Message) {
+	var data struct {
+		TournamentID string `json:"tournamentId"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := h.tournaments.Unregister(data.TournamentID, client.PlayerID); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+	client.Send(NewMessage("tournament_unregistered", map[string]string{"tournamentId": data.TournamentID}))
+}
+
+// handleTournamentPurchase sells a player chips at their tournament table:
+// a rebuy or the add-on.
+func (h *Handler) handleTournamentPurchase(client *Client, msg *Message, reply string, buy func(id, playerID string) error) {
+	var data struct {
+		TournamentID string `json:"tournamentId"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := buy(data.TournamentID, client.PlayerID); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+		return
+	}
+	client.Send(NewMessage(reply, map[string]string{"tournamentId": data.TournamentID}))
+	h.sendGameState(client.RoomID)
+}
+
+// handleProposeDeal offers the final table a deal, priced by "icm" or
+// "chip", optionally keeping a remainder back to play on for.
+func (h *Handler) handleProposeDeal(client *Client, msg *Message) {
+	var data struct {
+		TournamentID string `json:"tournamentId"`
+		Method       string `json:"method"`
+		Remainder    int64  `json:"remainder"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+	if data.Method == "" {
+		data.Method = "icm"
+	}
+
+	if err := h.tournaments.ProposeDeal(data.TournamentID, client.PlayerID, data.Method, data.Remainder); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+	}
+}
+
+func (h *Handler) handleAnswerDeal(client *Client, msg *Message, answer func(id, playerID string) error) {
+	var data struct {
+		TournamentID string `json:"tournamentId"`
+	}
+	if err := msg.ParseData(&data); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": "invalid request"}))
+		return
+	}
+
+	if err := answer(data.TournamentID, client.PlayerID); err != nil {
+		client.Send(NewMessage("error", map[string]string{"message": err.Error()}))
+	}
 }
@@ -172,6 +172,25 @@ func (h *Hub) GetRoomClients(roomID string) []*Client {
 	return clients
 }
 
+// GetPlayerClients returns every connection of the given players.
+func (h *Hub) GetPlayerClients(playerIDs []string) []*Client {
+	wanted := make(map[string]bool, len(playerIDs))
+	for _, id := range playerIDs {
+		wanted[id] = true
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0)
+	for _, client := range h.clients {
+		if wanted[client.PlayerID] {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
 func (h *Hub) GetClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()